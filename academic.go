@@ -0,0 +1,239 @@
+package search
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// paperColumns are the fields of academic results, which -columns
+// can add.
+var paperColumns = []string{"authors", "year", "doi", "venue", "pdf"}
+
+// academicQueries sets up the engines for searching papers.
+func (s *searcher) academicQueries() {
+	s.arxiv = &query{
+		base:      "https://export.arxiv.org/api/query?search_query=all:",
+		decode:    decodeArXiv,
+		name:      "arxiv",
+		pageParam: "start",
+		pageSize:  10,
+		pageStart: 0,
+	}
+	s.crossref = &query{
+		base:      "https://api.crossref.org/works?rows=10&query=",
+		decode:    decodeCrossref,
+		name:      "crossref",
+		pageParam: "offset",
+		pageSize:  10,
+		pageStart: 0,
+	}
+	s.semanticScholar = &query{
+		base:      "https://api.semanticscholar.org/graph/v1/paper/search?fields=title,url,abstract,authors,year,venue,externalIds,openAccessPdf&query=",
+		decode:    decodeSemanticScholar,
+		name:      "semanticscholar",
+		pageParam: "offset",
+		pageSize:  10,
+		pageStart: 0,
+	}
+}
+
+// arxivFeed is the part of arXiv's Atom feed we read.
+type arxivFeed struct {
+	Entries []struct {
+		ID        string `xml:"id"`
+		Published string `xml:"published"`
+		Title     string `xml:"title"`
+		Summary   string `xml:"summary"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Links []struct {
+			Href  string `xml:"href,attr"`
+			Title string `xml:"title,attr"`
+			Type  string `xml:"type,attr"`
+		} `xml:"link"`
+		DOI        string `xml:"http://arxiv.org/schemas/atom doi"`
+		JournalRef string `xml:"http://arxiv.org/schemas/atom journal_ref"`
+	} `xml:"entry"`
+}
+
+func decodeArXiv(body io.Reader) ([]result, error) {
+	var feed arxivFeed
+	err := xml.NewDecoder(body).Decode(&feed)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, e := range feed.Entries {
+		// titles and summaries are wrapped across lines
+		r := result{
+			Title: strings.Join(strings.Fields(e.Title), " "),
+			URL:   e.ID,
+			Blurb: strings.Join(strings.Fields(e.Summary), " "),
+			DOI:   e.DOI,
+			Venue: e.JournalRef,
+		}
+		if r.Venue == "" {
+			r.Venue = "arXiv"
+		}
+		for _, a := range e.Authors {
+			r.Authors = append(r.Authors, a.Name)
+		}
+		for _, l := range e.Links {
+			if l.Title == "pdf" || l.Type == "application/pdf" {
+				r.PDF = l.Href
+			}
+		}
+		if len(e.Published) >= 4 {
+			r.Year, _ = strconv.Atoi(e.Published[:4])
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func decodeCrossref(body io.Reader) ([]result, error) {
+	var resp struct {
+		Message struct {
+			Items []struct {
+				DOI    string   `json:"DOI"`
+				URL    string   `json:"URL"`
+				Title  []string `json:"title"`
+				Author []struct {
+					Given  string `json:"given"`
+					Family string `json:"family"`
+					Name   string `json:"name"`
+				} `json:"author"`
+				ContainerTitle []string `json:"container-title"`
+				Issued         struct {
+					DateParts [][]int `json:"date-parts"`
+				} `json:"issued"`
+				Abstract string `json:"abstract"`
+				Link     []struct {
+					URL         string `json:"URL"`
+					ContentType string `json:"content-type"`
+				} `json:"link"`
+			} `json:"items"`
+		} `json:"message"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, item := range resp.Message.Items {
+		r := result{
+			URL: item.URL,
+			// abstracts are jats xml
+			Blurb: stripTags(item.Abstract),
+			DOI:   item.DOI,
+		}
+		if item.DOI != "" {
+			r.URL = "https://doi.org/" + item.DOI
+		}
+		if len(item.Title) > 0 {
+			r.Title = item.Title[0]
+		}
+		if len(item.ContainerTitle) > 0 {
+			r.Venue = item.ContainerTitle[0]
+		}
+		for _, a := range item.Author {
+			name := strings.TrimSpace(a.Given + " " + a.Family)
+			if name == "" {
+				name = a.Name
+			}
+			r.Authors = append(r.Authors, name)
+		}
+		if parts := item.Issued.DateParts; len(parts) > 0 && len(parts[0]) > 0 {
+			r.Year = parts[0][0]
+		}
+		for _, l := range item.Link {
+			if l.ContentType == "application/pdf" {
+				r.PDF = l.URL
+				break
+			}
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func decodeSemanticScholar(body io.Reader) ([]result, error) {
+	var resp struct {
+		Data []struct {
+			URL      string `json:"url"`
+			Title    string `json:"title"`
+			Abstract string `json:"abstract"`
+			Venue    string `json:"venue"`
+			Year     int    `json:"year"`
+			Authors  []struct {
+				Name string `json:"name"`
+			} `json:"authors"`
+			ExternalIDs struct {
+				DOI string `json:"DOI"`
+			} `json:"externalIds"`
+			OpenAccessPDF *struct {
+				URL string `json:"url"`
+			} `json:"openAccessPdf"`
+		} `json:"data"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, p := range resp.Data {
+		r := result{
+			Title: p.Title,
+			URL:   p.URL,
+			Blurb: p.Abstract,
+			DOI:   p.ExternalIDs.DOI,
+			Venue: p.Venue,
+			Year:  p.Year,
+		}
+		for _, a := range p.Authors {
+			r.Authors = append(r.Authors, a.Name)
+		}
+		if p.OpenAccessPDF != nil {
+			r.PDF = p.OpenAccessPDF.URL
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// citation is a short author list and year, like "Pike, Thompson,
+// and Griesemer 2009" or "Pike et al. 2009".
+func (r result) citation() string {
+	var names []string
+	for _, a := range r.Authors {
+		names = append(names, lastName(a))
+	}
+	var c string
+	switch len(names) {
+	case 0:
+	case 1:
+		c = names[0]
+	case 2:
+		c = names[0] + " and " + names[1]
+	case 3:
+		c = names[0] + ", " + names[1] + ", and " + names[2]
+	default:
+		c = names[0] + " et al."
+	}
+	if r.Year > 0 {
+		c = strings.TrimSpace(c + " " + strconv.Itoa(r.Year))
+	}
+	return c
+}
+
+func lastName(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
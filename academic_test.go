@@ -0,0 +1,101 @@
+package search_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+func TestGroupAcademic(t *testing.T) {
+	t.Parallel()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "go concurrency", "-n", "-group", "academic", "-page", "2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	var urls []string
+	for u := range s.FormatURL() {
+		urls = append(urls, u)
+	}
+	want := []string{
+		"https://export.arxiv.org/api/query?search_query=all:go+concurrency&start=10",
+		"https://api.crossref.org/works?rows=10&query=go+concurrency&offset=10",
+		"https://api.semanticscholar.org/graph/v1/paper/search?fields=title,url,abstract,authors,year,venue,externalIds,openAccessPdf&query=go+concurrency&offset=10",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("want %v, got %v", want, urls)
+	}
+}
+
+func TestPaperFields(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	sv, err := search.NewServer(nil, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		engine string
+		want   search.Result
+	}{
+		{
+			engine: "arxiv",
+			want: search.Result{
+				Authors: []string{"Tengfei Tu", "Xiaoyu Liu", "Linhai Song", "Yiying Zhang"},
+				Year:    2022,
+				DOI:     "10.1145/3297858.3304069",
+				Venue:   "ASPLOS '19",
+				PDF:     "http://arxiv.org/pdf/2204.00764v1",
+			},
+		},
+		{
+			engine: "crossref",
+			want: search.Result{
+				Authors: []string{"Jeff Meyerson"},
+				Year:    2014,
+				DOI:     "10.1109/MS.2016.6",
+				Venue:   "IEEE Software",
+				PDF:     "https://example.org/meyerson2014.pdf",
+			},
+		},
+		{
+			engine: "semanticscholar",
+			want: search.Result{
+				Authors: []string{"Kim Lee", "Sam Park"},
+				Year:    2023,
+				PDF:     "https://example.org/gorust.pdf",
+			},
+		},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&engines="+tt.engine, nil))
+		var resp struct {
+			Results []search.Result `json:"results"`
+		}
+		err = json.NewDecoder(rec.Body).Decode(&resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got *search.Result
+		for i, r := range resp.Results {
+			if r.Year == tt.want.Year {
+				got = &resp.Results[i]
+			}
+		}
+		if got == nil {
+			t.Errorf("%s: no result from %d in %+v", tt.engine, tt.want.Year, resp.Results)
+			continue
+		}
+		if !reflect.DeepEqual(got.Authors, tt.want.Authors) || got.DOI != tt.want.DOI || got.Venue != tt.want.Venue || got.PDF != tt.want.PDF {
+			t.Errorf("%s: want %+v, got %+v", tt.engine, tt.want, *got)
+		}
+	}
+}
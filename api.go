@@ -0,0 +1,118 @@
+package search
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"regexp"
+)
+
+// apiKeyEnv names the environment variable holding each engine's
+// api key. With a key, bing and brave are searched through their
+// official apis instead of by scraping, and github allows more
+// searches and searching code.
+var apiKeyEnv = map[string]string{
+	"bing":   "BING_API_KEY",
+	"brave":  "BRAVE_API_KEY",
+	"github": "GITHUB_TOKEN",
+}
+
+// apiQueries swaps in the api versions of engines that have a key.
+func (s *searcher) apiQueries() {
+	if key := s.apiKeys["brave"]; key != "" {
+		s.brave = &query{
+			base:   "https://api.search.brave.com/res/v1/web/search?q=",
+			decode: decodeBraveAPI,
+			header: map[string]string{
+				"Accept":               "application/json",
+				"X-Subscription-Token": key,
+			},
+			localize:  braveAPILocale,
+			name:      "brave",
+			pageParam: "offset",
+			pageSize:  1,
+			pageStart: 0,
+			since:     sinceParams("freshness", "pd", "pw", "pm", "py"),
+			verify:    true,
+		}
+	}
+	if key := s.apiKeys["bing"]; key != "" {
+		s.bing = &query{
+			base:   "https://api.bing.microsoft.com/v7.0/search?q=",
+			decode: decodeBingAPI,
+			header: map[string]string{
+				"Accept":                    "application/json",
+				"Ocp-Apim-Subscription-Key": key,
+			},
+			localize:  bingAPILocale,
+			name:      "bing",
+			pageParam: "offset",
+			pageSize:  10,
+			pageStart: 0,
+			since:     sinceParams("freshness", "Day", "Week", "Month", ""),
+			verify:    true,
+		}
+	}
+}
+
+// decodeBraveAPI reads a Brave Search API web search response.
+func decodeBraveAPI(body io.Reader) ([]result, error) {
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				Age         string `json:"age"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Web.Results {
+		results = append(results, result{
+			Title: stripTags(r.Title),
+			URL:   r.URL,
+			Blurb: stripTags(r.Description),
+			Date:  r.Age,
+		})
+	}
+	return results, nil
+}
+
+// decodeBingAPI reads a Bing Web Search API response.
+func decodeBingAPI(body io.Reader) ([]result, error) {
+	var resp struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.WebPages.Value {
+		results = append(results, result{
+			Title: r.Name,
+			URL:   r.URL,
+			Blurb: r.Snippet,
+		})
+	}
+	return results, nil
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags turns the bits of html some apis put in their text,
+// like <strong> around matches, into plain text.
+func stripTags(str string) string {
+	return html.UnescapeString(tags.ReplaceAllString(str, ""))
+}
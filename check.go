@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// linkCheck is the outcome of requesting a result's URL.
+type linkCheck struct {
+	Status      int      `json:"status"`
+	FinalURL    string   `json:"final_url"`
+	Redirects   []string `json:"redirects,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// dead reports whether the link is broken: it couldn't be reached,
+// or it ended in a 4xx or 5xx.
+func (c *linkCheck) dead() bool {
+	return c.Error != "" || c.Status >= 400
+}
+
+// checkLinks passes results through with the outcome of requesting
+// each URL. Each URL is only checked once, however many engines
+// return it. With s.live, dead links are dropped.
+func (s *searcher) checkLinks(ctx context.Context, in <-chan result) <-chan result {
+	var mu sync.Mutex
+	checks := make(map[string]*checkCall)
+	all := func(r result) bool {
+		return r.URL != ""
+	}
+	return s.process(ctx, in, all, func(ctx context.Context, r *result) bool {
+		mu.Lock()
+		call, ok := checks[r.URL]
+		if !ok {
+			call = &checkCall{done: make(chan struct{})}
+			checks[r.URL] = call
+		}
+		mu.Unlock()
+		if ok {
+			<-call.done
+		} else {
+			call.check = s.checkLink(ctx, r.URL)
+			close(call.done)
+		}
+		c := call.check
+		r.Check = &c
+		return !s.live || !c.dead()
+	})
+}
+
+// checkCall is a link check shared by results with the same URL.
+type checkCall struct {
+	done  chan struct{}
+	check linkCheck
+}
+
+// checkLink requests u with browser headers, following redirects.
+// It tries HEAD first, falling back to GET for servers that refuse
+// or mishandle HEAD.
+func (s *searcher) checkLink(ctx context.Context, u string) linkCheck {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.timeout)*time.Millisecond)
+	defer cancel()
+
+	c := s.checkWith(ctx, http.MethodHead, u)
+	if c.dead() {
+		c = s.checkWith(ctx, http.MethodGet, u)
+	}
+	return c
+}
+
+func (s *searcher) checkWith(ctx context.Context, method, u string) linkCheck {
+	resp, chain, err := s.follow(ctx, method, u)
+	c := linkCheck{FinalURL: chain[len(chain)-1]}
+	if len(chain) > 1 {
+		c.Redirects = chain[:len(chain)-1]
+	}
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	// drain a little so the connection can be reused
+	io.CopyN(io.Discard, resp.Body, 4096)
+	resp.Body.Close()
+	c.Status = resp.StatusCode
+	c.ContentType = strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	return c
+}
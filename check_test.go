@@ -0,0 +1,101 @@
+package search_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+func linkServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestCheckLinks(t *testing.T) {
+	t.Parallel()
+	ts := linkServer(t)
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "foo", "-n", "-check"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.CheckLinks([]search.Result{
+		{URL: ts.URL + "/ok"},
+		{URL: ts.URL + "/moved"},
+		{URL: ts.URL + "/nohead"},
+		{URL: ts.URL + "/gone"},
+	})
+	if len(got) != 4 {
+		t.Fatalf("got %d results want 4", len(got))
+	}
+	checks := make(map[string]search.Result)
+	for _, r := range got {
+		checks[r.URL[len(ts.URL):]] = r
+	}
+
+	ok := checks["/ok"].Check
+	if ok.Status != 200 || ok.ContentType != "text/html" || ok.FinalURL != ts.URL+"/ok" || ok.Redirects != nil {
+		t.Errorf("/ok: got %+v", ok)
+	}
+	moved := checks["/moved"].Check
+	if moved.Status != 200 || moved.FinalURL != ts.URL+"/ok" || !reflect.DeepEqual(moved.Redirects, []string{ts.URL + "/moved"}) {
+		t.Errorf("/moved: got %+v", moved)
+	}
+	nohead := checks["/nohead"].Check
+	if nohead.Status != 200 || nohead.ContentType != "application/pdf" {
+		t.Errorf("/nohead: got %+v", nohead)
+	}
+	if gone := checks["/gone"].Check; gone.Status != 404 {
+		t.Errorf("/gone: got %+v", gone)
+	}
+}
+
+func TestCheckLinksLive(t *testing.T) {
+	t.Parallel()
+	ts := linkServer(t)
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "foo", "-n", "-live"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.CheckLinks([]search.Result{
+		{Engine: "brave", URL: ts.URL + "/ok"},
+		{Engine: "mojeek", URL: ts.URL + "/ok"},
+		{Engine: "brave", URL: ts.URL + "/gone"},
+		{Engine: "brave", URL: "http://127.0.0.1:0/unreachable"},
+	})
+	var engines []string
+	for _, r := range got {
+		if r.URL != ts.URL+"/ok" {
+			t.Errorf("want dead link %s dropped", r.URL)
+		}
+		engines = append(engines, r.Engine)
+	}
+	sort.Strings(engines)
+	if !reflect.DeepEqual(engines, []string{"brave", "mojeek"}) {
+		t.Errorf("got engines %v", engines)
+	}
+}
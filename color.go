@@ -0,0 +1,90 @@
+package search
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ansi escape codes used when highlighting output.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiYellow = "\x1b[1;33m"
+	ansiCyan   = "\x1b[36m"
+	ansiGreen  = "\x1b[32m"
+	ansiRed    = "\x1b[31m"
+)
+
+// useColor decides whether output should be colored, based on
+// the -color mode, the NO_COLOR environment variable, and whether
+// s.output is a terminal.
+func (s *searcher) useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(s.output)
+}
+
+// isTerminal reports whether w is a character device.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// termMatcher builds a case-insensitive regexp matching the base
+// search term(s) and any additional terms. It returns nil if there
+// is nothing to highlight.
+func (s *searcher) termMatcher() *regexp.Regexp {
+	seen := make(map[string]bool)
+	var words []string
+	for _, t := range append([]string{s.search}, s.terms...) {
+		for _, w := range strings.Split(t, "+") {
+			w = strings.ToLower(strings.Trim(w, `"`))
+			if w == "" || seen[w] {
+				continue
+			}
+			seen[w] = true
+			words = append(words, regexp.QuoteMeta(w))
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	// prefer the longest match when terms overlap
+	sort.Slice(words, func(i, j int) bool {
+		return len(words[i]) > len(words[j])
+	})
+	return regexp.MustCompile(`(?i)(` + strings.Join(words, "|") + `)`)
+}
+
+// highlight wraps each occurrence of a query term in str with
+// ansi codes. It is a no-op when color is disabled.
+func (s *searcher) highlight(str string) string {
+	if !s.color || s.termMatch == nil {
+		return str
+	}
+	return s.termMatch.ReplaceAllString(str, ansiYellow+"$1"+ansiReset)
+}
+
+// paint wraps str in the given ansi code when color is enabled.
+func (s *searcher) paint(code, str string) string {
+	if !s.color || str == "" {
+		return str
+	}
+	return code + str + ansiReset
+}
@@ -0,0 +1,86 @@
+package search_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+func TestHighlightTerms(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "go lang", "-color", "always", "cli"}
+	s, err := search.NewSearcher(
+		search.WithOutput(io.Discard),
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Highlight("A Go CLI for golang")
+	want := "A \x1b[1;33mGo\x1b[0m \x1b[1;33mCLI\x1b[0m for \x1b[1;33mgo\x1b[0m\x1b[1;33mlang\x1b[0m"
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestHighlightNever(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "go", "-color", "never", "cli"}
+	s, err := search.NewSearcher(
+		search.WithOutput(io.Discard),
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "A Go CLI"
+	got := s.Highlight(want)
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestHighlightAutoNotTerminal(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "go", "cli"}
+	s, err := search.NewSearcher(
+		search.WithOutput(io.Discard),
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "A Go CLI"
+	got := s.Highlight(want)
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestColorText(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&buf),
+		search.FromArgs([]string{"-s", "go", "-n", "-color", "always"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write([]search.Result{{Engine: "brave", Title: "The Go Programming Language", URL: "https://go.dev/", Blurb: "Go is fast"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"The \x1b[1;33mGo\x1b[0m Programming Language \x1b[32m[brave]\x1b[0m\n",
+		"\x1b[36mhttps://go.dev/\x1b[0m\n",
+		"\x1b[1;33mGo\x1b[0m is fast\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want %q in %q", want, buf.String())
+		}
+	}
+}
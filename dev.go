@@ -0,0 +1,241 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// engineGroups are the engines -group searches by name.
+var engineGroups = map[string][]string{
+	"academic":  {"arxiv", "crossref", "semanticscholar"},
+	"dev":       {"github", "stackoverflow", "pkgsite", "hn"},
+	"reference": {"wikipedia", "wiktionary"},
+}
+
+// groupEngines returns the engines in group. githubcode joins dev
+// when there's a token to search code with.
+func (s *searcher) groupEngines(group string) []string {
+	names := append([]string{}, engineGroups[group]...)
+	if group == "dev" && s.apiKeys["github"] != "" {
+		names = append(names, "githubcode")
+	}
+	return names
+}
+
+// devQueries sets up the engines for searching code and talk about
+// it.
+func (s *searcher) devQueries() {
+	github := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if token := s.apiKeys["github"]; token != "" {
+		github["Authorization"] = "Bearer " + token
+	}
+	s.github = &query{
+		base:      "https://api.github.com/search/repositories?q=",
+		decode:    decodeGitHubRepos,
+		header:    github,
+		name:      "github",
+		pageParam: "page",
+		pageSize:  1,
+		pageStart: 1,
+		verify:    true,
+	}
+	// code search needs a token
+	s.githubCode = &query{name: "githubcode"}
+	if s.apiKeys["github"] != "" {
+		code := make(map[string]string)
+		for k, v := range github {
+			code[k] = v
+		}
+		// include the matching lines
+		code["Accept"] = "application/vnd.github.text-match+json"
+		s.githubCode = &query{
+			base:      "https://api.github.com/search/code?q=",
+			decode:    decodeGitHubCode,
+			header:    code,
+			name:      "githubcode",
+			pageParam: "page",
+			pageSize:  1,
+			pageStart: 1,
+			verify:    true,
+		}
+	}
+	s.hn = &query{
+		base:      "https://hn.algolia.com/api/v1/search?tags=story&query=",
+		decode:    decodeHN,
+		name:      "hn",
+		pageParam: "page",
+		pageSize:  1,
+		pageStart: 0,
+	}
+	s.pkgsite = &query{
+		base:          "https://pkg.go.dev/search?q=",
+		blurbSelector: "p.SearchSnippet-synopsis",
+		extract: func(g *goquery.Selection, r *result) {
+			// links are relative to pkg.go.dev
+			if strings.HasPrefix(r.URL, "/") {
+				r.URL = "https://pkg.go.dev" + r.URL
+			}
+			r.Date = strings.TrimSpace(g.Find("span[data-test-id='snippet-published'] strong").Text())
+		},
+		itemSelector:  "div.SearchSnippet",
+		linkSelector:  "div.SearchSnippet-headerContainer h2 a",
+		name:          "pkgsite",
+		pageParam:     "page",
+		pageSize:      1,
+		pageStart:     1,
+		titleSelector: "div.SearchSnippet-headerContainer h2 a",
+	}
+	s.stackoverflow = &query{
+		base:      "https://api.stackexchange.com/2.3/search/advanced?order=desc&sort=relevance&site=stackoverflow&q=",
+		decode:    decodeStackExchange,
+		name:      "stackoverflow",
+		pageParam: "page",
+		pageSize:  1,
+		pageStart: 1,
+		verify:    true,
+	}
+}
+
+func decodeGitHubRepos(body io.Reader) ([]result, error) {
+	var resp struct {
+		Items []struct {
+			FullName    string `json:"full_name"`
+			HTMLURL     string `json:"html_url"`
+			Description string `json:"description"`
+			Stars       int    `json:"stargazers_count"`
+			Language    string `json:"language"`
+			PushedAt    string `json:"pushed_at"`
+		} `json:"items"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Items {
+		stats := fmt.Sprintf("%d stars", r.Stars)
+		if r.Language != "" {
+			stats = r.Language + ", " + stats
+		}
+		results = append(results, result{
+			Title: r.FullName,
+			URL:   r.HTMLURL,
+			Blurb: strings.TrimSpace(r.Description + " (" + stats + ")"),
+			Date:  r.PushedAt,
+		})
+	}
+	return results, nil
+}
+
+func decodeGitHubCode(body io.Reader) ([]result, error) {
+	var resp struct {
+		Items []struct {
+			Path       string `json:"path"`
+			HTMLURL    string `json:"html_url"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+			TextMatches []struct {
+				Fragment string `json:"fragment"`
+			} `json:"text_matches"`
+		} `json:"items"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Items {
+		var fragments []string
+		for _, m := range r.TextMatches {
+			fragments = append(fragments, m.Fragment)
+		}
+		results = append(results, result{
+			Title:  r.Repository.FullName + "/" + r.Path,
+			URL:    r.HTMLURL,
+			Blurb:  strings.Join(fragments, " … "),
+			Source: r.Repository.FullName,
+		})
+	}
+	return results, nil
+}
+
+func decodeHN(body io.Reader) ([]result, error) {
+	var resp struct {
+		Hits []struct {
+			ObjectID    string `json:"objectID"`
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			StoryText   string `json:"story_text"`
+			Author      string `json:"author"`
+			Points      int    `json:"points"`
+			NumComments int    `json:"num_comments"`
+			CreatedAt   string `json:"created_at"`
+		} `json:"hits"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Hits {
+		discussion := "https://news.ycombinator.com/item?id=" + r.ObjectID
+		u := r.URL
+		if u == "" {
+			// Ask HN and the like have no link of their own
+			u = discussion
+		}
+		blurb := fmt.Sprintf("%d points by %s, %d comments: %s", r.Points, r.Author, r.NumComments, discussion)
+		if r.StoryText != "" {
+			blurb = stripTags(r.StoryText) + " (" + blurb + ")"
+		}
+		results = append(results, result{
+			Title: r.Title,
+			URL:   u,
+			Blurb: blurb,
+			Date:  r.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+func decodeStackExchange(body io.Reader) ([]result, error) {
+	var resp struct {
+		Items []struct {
+			Title       string   `json:"title"`
+			Link        string   `json:"link"`
+			Tags        []string `json:"tags"`
+			Score       int      `json:"score"`
+			AnswerCount int      `json:"answer_count"`
+			IsAnswered  bool     `json:"is_answered"`
+		} `json:"items"`
+		ErrorMessage string `json:"error_message"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ErrorMessage != "" {
+		return nil, fmt.Errorf("stack exchange: %s", resp.ErrorMessage)
+	}
+	var results []result
+	for _, r := range resp.Items {
+		answered := ""
+		if r.IsAnswered {
+			answered = ", answered"
+		}
+		results = append(results, result{
+			Title: stripTags(r.Title),
+			URL:   r.Link,
+			Blurb: fmt.Sprintf("score %d, %d answers%s, tagged %s", r.Score, r.AnswerCount, answered, strings.Join(r.Tags, ", ")),
+		})
+	}
+	return results, nil
+}
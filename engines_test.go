@@ -0,0 +1,482 @@
+package search_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+// engineResults searches a single fake engine through the server.
+func engineResults(t *testing.T, engine string) searchResponse {
+	t.Helper()
+	_, opts := fakeEngines(t)
+	sv, err := search.NewServer(nil, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&engines="+engine, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp searchResponse
+	err = json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestEngineSelectors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		engine string
+		urls   []string
+		title  string
+		blurb  string
+	}{
+		{
+			engine: "startpage",
+			urls:   []string{"https://go.dev/", "https://github.com/urfave/cli"},
+			title:  "The Go Programming Language",
+			blurb:  "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+		},
+		{
+			engine: "ecosia",
+			// the ad is skipped
+			urls:  []string{"https://go.dev/", "https://github.com/spf13/cobra"},
+			title: "The Go Programming Language",
+			blurb: "Go is an open source programming language supported by Google. Easy to learn and great for teams.",
+		},
+		{
+			engine: "github",
+			urls:   []string{"https://github.com/spf13/cobra", "https://github.com/urfave/cli"},
+			title:  "spf13/cobra",
+			blurb:  "A Commander for modern Go CLI interactions (Go, 31254 stars)",
+		},
+		{
+			engine: "hn",
+			// ask hn links to the discussion
+			urls:  []string{"https://go.dev/blog/go1.20", "https://news.ycombinator.com/item?id=33545511"},
+			title: "Go 1.20 Released",
+			blurb: "412 points by petercooper, 187 comments: https://news.ycombinator.com/item?id=34616054",
+		},
+		{
+			engine: "pkgsite",
+			urls:   []string{"https://pkg.go.dev/github.com/spf13/cobra", "https://pkg.go.dev/github.com/urfave/cli/v2"},
+			title:  "cobra (github.com/spf13/cobra)",
+			blurb:  "Package cobra is a commander providing a simple interface to create powerful modern CLI interfaces.",
+		},
+		{
+			engine: "stackoverflow",
+			urls:   []string{"https://stackoverflow.com/questions/20105060/how-to-stop-a-goroutine", "https://stackoverflow.com/questions/75240001/context-cancel-vs-timeout"},
+			title:  "How to stop a goroutine?",
+			blurb:  "score 201, 4 answers, answered, tagged go, goroutine, channel",
+		},
+		{
+			engine: "wikipedia",
+			urls:   []string{"https://en.wikipedia.org/wiki/Go_(programming_language)", "https://en.wikipedia.org/wiki/Gopher_Protocol"},
+			title:  "Go (programming language)",
+			// without the searchmatch spans
+			blurb: `Go is a statically typed, compiled high-level programming language designed at Google by Robert Griesemer, Rob Pike, and Ken Thompson. It "is" syntactically`,
+		},
+		{
+			engine: "wiktionary",
+			urls:   []string{"https://en.wiktionary.org/wiki/gopher", "https://en.wiktionary.org/wiki/go%3F"},
+			title:  "gopher",
+			blurb:  `English Etymology From Louisiana French gaufre ("honeycomb")`,
+		},
+		{
+			engine: "arxiv",
+			urls:   []string{"http://arxiv.org/abs/2204.00764v1", "http://arxiv.org/abs/2101.12345v2"},
+			title:  "Understanding Real-World Concurrency Bugs in Go",
+			blurb:  "Go is a statically-typed programming language that aims to provide a simple, efficient, and safe way to build multi-threaded software.",
+		},
+		{
+			engine: "crossref",
+			urls:   []string{"https://doi.org/10.1145/3297858.3304069", "https://doi.org/10.1109/MS.2016.6"},
+			title:  "Understanding Real-World Concurrency Bugs in Go",
+			blurb:  "Go is a statically-typed programming language that aims to provide a simple, efficient, and safe way to build multi-threaded software.",
+		},
+		{
+			engine: "semanticscholar",
+			urls: []string{
+				"https://www.semanticscholar.org/paper/8f6d6c0b1ab1c5b4a0a0a1e3f0c0c9b5e1a2d3c4",
+				"https://www.semanticscholar.org/paper/1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d",
+			},
+			title: "Understanding Real-World Concurrency Bugs in Go",
+			blurb: "Go is a statically-typed programming language that aims to provide a simple, efficient, and safe way to build multi-threaded software.",
+		},
+		{
+			engine: "searxng",
+			urls:   []string{"https://go.dev/", "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+			title:  "The Go Programming Language",
+			blurb:  "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+		},
+	}
+	for _, tt := range tests {
+		resp := engineResults(t, tt.engine)
+		if len(resp.Results) != len(tt.urls) {
+			t.Fatalf("%s: want %d results, got %+v", tt.engine, len(tt.urls), resp.Results)
+		}
+		for i, r := range resp.Results {
+			if r.Engine != tt.engine || r.URL != tt.urls[i] {
+				t.Errorf("%s: want %s, got %+v", tt.engine, tt.urls[i], r)
+			}
+		}
+		if resp.Results[0].Title != tt.title {
+			t.Errorf("%s: want title %q, got %q", tt.engine, tt.title, resp.Results[0].Title)
+		}
+		if resp.Results[0].Blurb != tt.blurb {
+			t.Errorf("%s: want blurb %q, got %q", tt.engine, tt.blurb, resp.Results[0].Blurb)
+		}
+	}
+}
+
+// TestSearXNGEnv checks that $SEARXNG_URL only reaches a searcher
+// through RunCLI's options, and then only for -engines searxng.
+func TestSearXNGEnv(t *testing.T) {
+	t.Setenv("SEARXNG_URL", "https://searx.example.org")
+	tests := []struct {
+		opts []search.Option
+		args []string
+		want bool
+	}{
+		{nil, nil, false},
+		{search.EnvOptions(), nil, false},
+		{search.EnvOptions(), []string{"-engines", "searxng"}, true},
+	}
+	for _, tt := range tests {
+		args := append([]string{"-s", "golang", "-n"}, tt.args...)
+		s, err := search.NewSearcher(append(tt.opts, search.FromArgs(args))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.CreateQueries()
+		var searched bool
+		for u := range s.FormatURL() {
+			if strings.HasPrefix(u, "https://searx.example.org/search?format=json&q=golang") {
+				searched = true
+			}
+		}
+		if searched != tt.want {
+			t.Errorf("%d options, %v: want searxng searched %t", len(tt.opts), tt.args, tt.want)
+		}
+	}
+}
+
+func TestSearXNGURL(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&buf),
+		search.FromArgs([]string{"-s", "golang", "-n", "-page", "2", "-searxng", "https://searx.example.org/"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	var urls []string
+	for u := range s.FormatURL() {
+		urls = append(urls, u)
+	}
+	// searched along with the privacy engines
+	want := "https://searx.example.org/search?format=json&q=golang&pageno=2"
+	if len(urls) != 6 || urls[5] != want {
+		t.Errorf("want %s last of 6, got %v", want, urls)
+	}
+}
+
+func TestAPIEngines(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		engine string
+		header string
+		urls   []string
+		title  string
+		blurb  string
+	}{
+		{
+			engine: "brave",
+			header: "X-Subscription-Token",
+			urls:   []string{"https://go.dev/", "https://go.dev/doc/"},
+			title:  "The Go Programming Language",
+			blurb:  "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+		},
+		{
+			engine: "bing",
+			header: "Ocp-Apim-Subscription-Key",
+			urls:   []string{"https://go.dev/", "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+			title:  "The Go Programming Language",
+			blurb:  "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.engine, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(tt.header) != "secret" {
+					http.Error(w, "bad key", http.StatusUnauthorized)
+					return
+				}
+				http.ServeFile(w, r, "testdata/"+tt.engine+"-api.json")
+			}))
+			defer ts.Close()
+			sv, err := search.NewServer(nil, time.Second,
+				search.WithAPIKey(tt.engine, "secret"),
+				search.WithBaseURL(tt.engine, ts.URL+"/search?q="),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&engines="+tt.engine, nil))
+			var resp searchResponse
+			err = json.NewDecoder(rec.Body).Decode(&resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != len(tt.urls) {
+				t.Fatalf("want %d results, got %+v", len(tt.urls), resp)
+			}
+			for i, r := range resp.Results {
+				if r.Engine != tt.engine || r.URL != tt.urls[i] {
+					t.Errorf("want %s, got %+v", tt.urls[i], r)
+				}
+			}
+			if resp.Results[0].Title != tt.title {
+				t.Errorf("want title %q, got %q", tt.title, resp.Results[0].Title)
+			}
+			if resp.Results[0].Blurb != tt.blurb {
+				t.Errorf("want blurb %q, got %q", tt.blurb, resp.Results[0].Blurb)
+			}
+		})
+	}
+}
+
+// TestAPIVerifiesCertificates checks that api keys and tokens are
+// only sent to servers with a certificate the client trusts, even
+// though scraping skips verification.
+func TestAPIVerifiesCertificates(t *testing.T) {
+	t.Parallel()
+	for _, engine := range []string{"brave", "github", "stackoverflow"} {
+		engine := engine
+		t.Run(engine, func(t *testing.T) {
+			t.Parallel()
+			var sent int32
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&sent, 1)
+				if engine == "brave" {
+					http.ServeFile(w, r, "testdata/brave-api.json")
+					return
+				}
+				http.ServeFile(w, r, "testdata/"+engine+".json")
+			}))
+			defer ts.Close()
+			tests := []struct {
+				opts []search.Option
+				want int
+			}{
+				{nil, 0},
+				{[]search.Option{search.WithVerifiedClient(ts.Client())}, 2},
+			}
+			for _, tt := range tests {
+				sv, err := search.NewServer(nil, time.Second, append(tt.opts,
+					search.WithAPIKey("brave", "secret"),
+					search.WithAPIKey("github", "secret"),
+					search.WithBaseURL(engine, ts.URL+"/search?q="),
+				)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rec := httptest.NewRecorder()
+				sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&engines="+engine, nil))
+				var resp searchResponse
+				err = json.NewDecoder(rec.Body).Decode(&resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(resp.Results) != tt.want {
+					t.Errorf("want %d results, got %+v", tt.want, resp)
+				}
+			}
+			if n := atomic.LoadInt32(&sent); n != 1 {
+				t.Errorf("want one request, from the trusted client, got %d", n)
+			}
+		})
+	}
+}
+
+// TestAPIKeyEnv checks that keys in the environment only reach a
+// searcher through RunCLI's options.
+func TestAPIKeyEnv(t *testing.T) {
+	t.Setenv("BRAVE_API_KEY", "secret")
+	args := []string{"-s", "golang", "-n", "-engines", "brave"}
+	tests := []struct {
+		opts []search.Option
+		want string
+	}{
+		{nil, "https://search.brave.com/search?q=golang"},
+		{search.EnvOptions(), "https://api.search.brave.com/res/v1/web/search?q=golang"},
+	}
+	for _, tt := range tests {
+		s, err := search.NewSearcher(append(tt.opts, search.FromArgs(args))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.CreateQueries()
+		var urls []string
+		for u := range s.FormatURL() {
+			urls = append(urls, u)
+		}
+		if len(urls) != 1 || urls[0] != tt.want {
+			t.Errorf("want %s, got %v", tt.want, urls)
+		}
+	}
+}
+
+func TestAPIURLs(t *testing.T) {
+	t.Parallel()
+	s, err := search.NewSearcher(
+		search.WithAPIKey("brave", "secret"),
+		search.WithAPIKey("bing", "secret"),
+		search.FromArgs([]string{"-s", "golang", "-n", "-page", "2", "-engines", "brave,bing"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	var urls []string
+	for u := range s.FormatURL() {
+		urls = append(urls, u)
+	}
+	want := []string{
+		"https://api.search.brave.com/res/v1/web/search?q=golang&offset=1",
+		"https://api.bing.microsoft.com/v7.0/search?q=golang&offset=10",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("want %v, got %v", want, urls)
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	t.Parallel()
+	got := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("Accept-Language")
+		http.ServeFile(w, r, "testdata/mojeek.html")
+	}))
+	defer ts.Close()
+	sv, err := search.NewServer([]string{"-region", "ch", "-lang", "fr"}, time.Second,
+		search.WithBaseURL("mojeek", ts.URL+"/search?q="),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&engines=mojeek", nil))
+	if lang := <-got; lang != "fr-CH,fr;q=0.9" {
+		t.Errorf("want fr-CH,fr;q=0.9, got %q", lang)
+	}
+}
+
+func TestGitHubCode(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "requires authentication", http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, "testdata/githubcode.json")
+	}))
+	defer ts.Close()
+	sv, err := search.NewServer(nil, time.Second,
+		search.WithAPIKey("github", "secret"),
+		search.WithBaseURL("githubcode", ts.URL+"/search?q="),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=ExecuteContext&engines=githubcode", nil))
+	var resp searchResponse
+	err = json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("want 1 result, got %+v", resp)
+	}
+	r := resp.Results[0]
+	if r.Title != "spf13/cobra/command.go" || r.URL != "https://github.com/spf13/cobra/blob/4ed2ee3f/command.go" {
+		t.Errorf("got %+v", r)
+	}
+	if want := "func (c *Command) ExecuteContext(ctx context.Context) error { … c.ctx = ctx"; r.Blurb != want {
+		t.Errorf("want blurb %q, got %q", want, r.Blurb)
+	}
+}
+
+func TestGroupDev(t *testing.T) {
+	t.Parallel()
+	urls := func(opts ...search.Option) []string {
+		t.Helper()
+		s, err := search.NewSearcher(append(opts,
+			search.FromArgs([]string{"-s", "golang", "-n", "-group", "dev", "-engines", "duck"}),
+		)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.CreateQueries()
+		var urls []string
+		for u := range s.FormatURL() {
+			urls = append(urls, u)
+		}
+		return urls
+	}
+	want := []string{
+		"https://api.github.com/search/repositories?q=golang",
+		"https://api.stackexchange.com/2.3/search/advanced?order=desc&sort=relevance&site=stackoverflow&q=golang",
+		"https://pkg.go.dev/search?q=golang",
+		"https://hn.algolia.com/api/v1/search?tags=story&query=golang",
+		"https://html.duckduckgo.com/html?q=golang",
+	}
+	if got := urls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	// githubcode joins with a token
+	want = append(want[:4:4], "https://api.github.com/search/code?q=golang", want[4])
+	if got := urls(search.WithAPIKey("github", "secret")); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestWikiLang(t *testing.T) {
+	t.Parallel()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "gopher", "-n", "-group", "reference", "-lang", "de", "-page", "2"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	var urls []string
+	for u := range s.FormatURL() {
+		urls = append(urls, u)
+	}
+	want := []string{
+		"https://de.wikipedia.org/w/api.php?action=query&list=search&format=json&srsearch=gopher&sroffset=10",
+		"https://de.wiktionary.org/w/api.php?action=query&list=search&format=json&srsearch=gopher&sroffset=10",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("want %v, got %v", want, urls)
+	}
+}
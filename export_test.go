@@ -0,0 +1,183 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Highlight exposes highlight for testing.
+func (s *searcher) Highlight(str string) string {
+	return s.highlight(str)
+}
+
+// Result exposes result for testing.
+type Result = result
+
+// Browser exposes browser for testing.
+type Browser = browser
+
+// NewBrowser exposes newBrowser for testing.
+func (s *searcher) NewBrowser() *browser {
+	return s.newBrowser()
+}
+
+func (b *browser) Add(r result) {
+	b.add(r)
+}
+
+func (b *browser) Key(k string) {
+	b.handle(k)
+}
+
+// Rows returns the engines and url of each visible entry.
+func (b *browser) Rows() []string {
+	var rows []string
+	for _, e := range b.entries() {
+		rows = append(rows, strings.Join(e.Engines, ",")+" "+e.URL)
+	}
+	return rows
+}
+
+// Selected returns the url under the cursor.
+func (b *browser) Selected() string {
+	e, _ := b.selected()
+	return e.URL
+}
+
+// Render draws the browser into a width by height screen.
+func (b *browser) Render(width, height int) string {
+	var buf bytes.Buffer
+	b.render(&buf, width, height)
+	return buf.String()
+}
+
+var DecodeKeys = decodeKeys
+
+var (
+	Truncate      = truncate
+	TruncateCells = truncateCells
+	Wrap          = wrap
+)
+
+var EnvOptions = envOptions
+
+var (
+	ReplArgs  = replArgs
+	RunREPL   = runREPL
+	SplitArgs = splitArgs
+)
+
+// Option exposes option for testing.
+type Option = option
+
+func (o *openSearch) SetSuggestURL(u string) {
+	o.suggestURL = u
+}
+
+// PageText extracts the readable text of an html document.
+func (s *searcher) PageText(doc string) string {
+	d, err := goquery.NewDocumentFromReader(strings.NewReader(doc))
+	if err != nil {
+		panic(err)
+	}
+	return s.pageText(d)
+}
+
+// ReadPages runs results through readPages.
+func (s *searcher) ReadPages(results []result) []result {
+	return collect(s.readPages(context.Background(), resultChan(results)))
+}
+
+// CheckLinks runs results through checkLinks.
+func (s *searcher) CheckLinks(results []result) []result {
+	return collect(s.checkLinks(context.Background(), resultChan(results)))
+}
+
+func resultChan(results []result) <-chan result {
+	in := make(chan result, len(results))
+	for _, r := range results {
+		in <- r
+	}
+	close(in)
+	return in
+}
+
+func collect(ch <-chan result) []result {
+	var out []result
+	for r := range ch {
+		out = append(out, r)
+	}
+	return out
+}
+
+// MatchDomains reports whether host matches a -block style list.
+func MatchDomains(list, host string) (bool, error) {
+	d, err := parseDomains(list)
+	if err != nil {
+		return false, err
+	}
+	return d.match(host), nil
+}
+
+var RunHistory = runHistory
+
+// Record saves results to the history as RunCLI would.
+func (s *searcher) Record(results []result) {
+	s.CreateQueries()
+	s.record(results)
+}
+
+// SaveHistory stores a run of results in the history file at path.
+func SaveHistory(path string, when time.Time, queries []string, results []result) error {
+	h := &history{path: path}
+	return h.save(historyRun{Time: when, Queries: queries, Engines: []string{"brave"}}, results)
+}
+
+// Clock exposes clock for testing.
+type Clock = clock
+
+var NewWatcher = newWatcher
+
+// Run runs the watch with clock c, writing json when asJSON is set.
+func (w *watcher) Run(ctx context.Context, c clock, asJSON bool) error {
+	w.clock = c
+	w.json = asJSON
+	return w.run(ctx)
+}
+
+// Deliver runs results through the hooks, waiting a millisecond
+// between attempts.
+func (s *searcher) Deliver(results []result) []error {
+	s.retryDelay = time.Millisecond
+	return s.deliver(context.Background(), results)
+}
+
+// Write writes results as RunCLI would.
+func (s *searcher) Write(results []result) (int, error) {
+	_, n, err := s.write(resultChan(results))
+	return n, err
+}
+
+// WriteReport writes results as RunCLI would, after building the
+// search urls the report records.
+func (s *searcher) WriteReport(results []result) error {
+	s.CreateQueries()
+	for range s.FormatURL() {
+	}
+	_, _, err := s.write(resultChan(results))
+	return err
+}
+
+func (s *searcher) Skipped() []string {
+	return s.skipped()
+}
+
+func (s *searcher) Unlimited() []string {
+	return s.unlimited()
+}
+
+var LeadingDate = leadingDate
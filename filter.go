@@ -0,0 +1,216 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// domainList matches hosts against domains, which also match their
+// subdomains, and glob patterns, which match the whole host.
+type domainList struct {
+	domains  map[string]bool
+	patterns []string
+}
+
+// parseDomains reads a comma-separated list of domains and globs.
+// An entry starting with @ names a file of them, one per line, in
+// plain, hosts file, adblock (||example.com^), or uBlacklist
+// (*://*.example.com/*) form.
+func parseDomains(list string) (*domainList, error) {
+	d := &domainList{domains: make(map[string]bool)}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if !strings.HasPrefix(entry, "@") {
+			d.add(entry)
+			continue
+		}
+		f, err := os.Open(entry[1:])
+		if err != nil {
+			return nil, fmt.Errorf("unable to read domains: %w", err)
+		}
+		err = d.read(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read domains from %s: %w", entry[1:], err)
+		}
+	}
+	return d, nil
+}
+
+// read adds the domains in r, skipping blank lines, comments, and
+// adblock rules that aren't about whole domains.
+func (d *domainList) read(r io.Reader) error {
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		// hosts file: 0.0.0.0 example.com
+		if fields := strings.Fields(line); len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			for _, host := range fields[1:] {
+				if host != "localhost" {
+					d.add(host)
+				}
+			}
+			continue
+		}
+		// adblock: ||example.com^, ignoring element hiding and options
+		if strings.HasPrefix(line, "||") {
+			line = strings.TrimPrefix(line, "||")
+			i := strings.IndexAny(line, "^/$")
+			if i >= 0 && line[i] != '^' {
+				continue
+			}
+			if i >= 0 {
+				line = line[:i]
+			}
+		}
+		if strings.Contains(line, "##") || strings.HasPrefix(line, "@@") {
+			continue
+		}
+		d.add(line)
+	}
+	return scan.Err()
+}
+
+// add normalizes entry to a host or host pattern and adds it.
+func (d *domainList) add(entry string) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if i := strings.Index(entry, "://"); i >= 0 {
+		entry = entry[i+3:]
+	}
+	if i := strings.IndexAny(entry, "/?"); i >= 0 {
+		entry = entry[:i]
+	}
+	entry = strings.TrimSuffix(entry, ".")
+	// *.example.com covers example.com too, as in uBlacklist
+	if rest := strings.TrimPrefix(entry, "*."); rest != entry && !strings.ContainsAny(rest, "*?[") {
+		entry = rest
+	}
+	switch {
+	case entry == "" || entry == "*":
+		return
+	case strings.ContainsAny(entry, "*?["):
+		d.patterns = append(d.patterns, entry)
+	default:
+		d.domains[entry] = true
+	}
+}
+
+func (d *domainList) empty() bool {
+	return d == nil || (len(d.domains) == 0 && len(d.patterns) == 0)
+}
+
+// match reports whether host is on one of the domains or matches
+// one of the patterns.
+func (d *domainList) match(host string) bool {
+	if d == nil {
+		return false
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for h := host; h != ""; {
+		if d.domains[h] {
+			return true
+		}
+		i := strings.Index(h, ".")
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	for _, p := range d.patterns {
+		if ok, _ := path.Match(p, host); ok {
+			return true
+		}
+		// a leading *. matches no subdomain at all, too
+		if rest := strings.TrimPrefix(p, "*."); rest != p {
+			if ok, _ := path.Match(rest, host); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keep reports whether link passes -allow and -block. Links we can't
+// parse are only kept when there's no allowlist.
+func (s *searcher) keep(link string) bool {
+	if s.allow.empty() && s.block.empty() {
+		return true
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Hostname() == "" {
+		return s.allow.empty()
+	}
+	host := u.Hostname()
+	if !s.allow.empty() && !s.allow.match(host) {
+		return false
+	}
+	return !s.block.match(host)
+}
+
+// filtering reports whether -allow or -block is set.
+func (s *searcher) filtering() bool {
+	return !s.allow.empty() || !s.block.empty()
+}
+
+// parseBoosts reads a comma-separated list of domain=factor pairs.
+func parseBoosts(list string) (map[string]float64, error) {
+	boosts := make(map[string]float64)
+	for _, pair := range strings.Split(list, ",") {
+		domain, factor, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		f, err := strconv.ParseFloat(factor, 64)
+		if !ok || domain == "" || err != nil || f < 0 {
+			return nil, fmt.Errorf("%w: got %q", ErrInvalidBoost, pair)
+		}
+		boosts[strings.ToLower(domain)] = f
+	}
+	return boosts, nil
+}
+
+// boost returns the -boost factor for link's domain, preferring the
+// most specific domain listed, or 1 if none is.
+func (s *searcher) boost(link string) float64 {
+	if len(s.boosts) == 0 {
+		return 1
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return 1
+	}
+	for h := strings.ToLower(u.Hostname()); h != ""; {
+		if f, ok := s.boosts[h]; ok {
+			return f
+		}
+		i := strings.Index(h, ".")
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	return 1
+}
+
+// rank applies -boost to merged entries and sorts them by score,
+// highest first, keeping first-seen order between ties.
+func (s *searcher) rank(entries []entry) []entry {
+	for i := range entries {
+		entries[i].Score *= s.boost(entries[i].URL)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+	return entries
+}
@@ -0,0 +1,143 @@
+package search_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+func TestMatchDomains(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		list string
+		host string
+		want bool
+	}{
+		{"pinterest.com", "pinterest.com", true},
+		{"pinterest.com", "www.pinterest.com", true},
+		{"pinterest.com", "notpinterest.com", false},
+		{"Pinterest.com", "PINTEREST.COM", true},
+		{"quora.com,pinterest.com", "pinterest.com", true},
+		{"*.pinterest.*", "pinterest.co.uk", true},
+		{"*.pinterest.*", "www.pinterest.de", true},
+		{"*.quora.com", "quora.com", true},
+		{"*://*.quora.com/*", "es.quora.com", true},
+		{"https://example.com/path", "example.com", true},
+		{"spam*.net", "spammy.net", true},
+		{"spam*.net", "ham.net", false},
+	}
+	for _, tt := range tests {
+		got, err := search.MatchDomains(tt.list, tt.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q matching %q: want %v, got %v", tt.list, tt.host, tt.want, got)
+		}
+	}
+}
+
+func TestMatchDomainsFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	list := `# hosts file
+0.0.0.0 hosts.example
+127.0.0.1 localhost
+! adblock
+||adblock.example^
+||paths.example/ads
+example.org##.banner
+[Adblock Plus 2.0]
+*://*.ublacklist.example/*
+plain.example # trailing comment
+`
+	err := os.WriteFile(path, []byte(list), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"hosts.example", true},
+		{"localhost", false},
+		{"www.adblock.example", true},
+		{"paths.example", false},
+		{"example.org", false},
+		{"ublacklist.example", true},
+		{"plain.example", true},
+		{"inline.example", true},
+	}
+	for _, tt := range tests {
+		got, err := search.MatchDomains("inline.example,@"+path, tt.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: want %v, got %v", tt.host, tt.want, got)
+		}
+	}
+	_, err = search.MatchDomains("@"+path+".missing", "example.com")
+	if err == nil {
+		t.Fatal("want error for missing file")
+	}
+}
+
+// searxngURLs returns the result urls from a SearXNG search of the
+// fake engines, in order, run with args.
+func searxngURLs(t *testing.T, args ...string) []string {
+	t.Helper()
+	_, opts := fakeEngines(t)
+	args = append([]string{"-engines", "brave,mojeek"}, args...)
+	sx, err := search.NewSearXNG(args, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sx.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&format=json", nil))
+	var resp struct {
+		Results []struct {
+			URL string `json:"url"`
+		} `json:"results"`
+	}
+	err = json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, r := range resp.Results {
+		urls = append(urls, r.URL)
+	}
+	return urls
+}
+
+func TestBlockAllow(t *testing.T) {
+	t.Parallel()
+	for _, u := range searxngURLs(t, "-block", "pkg.go.dev") {
+		if u == "https://pkg.go.dev/flag" {
+			t.Fatalf("blocked url in results: %s", u)
+		}
+	}
+	urls := searxngURLs(t, "-allow", "github.com")
+	if len(urls) != 1 || urls[0] != "https://github.com/spf13/cobra" {
+		t.Fatalf("want only github.com results, got %v", urls)
+	}
+}
+
+func TestBoost(t *testing.T) {
+	t.Parallel()
+	urls := searxngURLs(t)
+	if len(urls) == 0 || urls[0] != "https://go.dev/" {
+		t.Fatalf("want go.dev first without boosts, got %v", urls)
+	}
+	urls = searxngURLs(t, "-boost", "github.com=10,go.dev=0.1")
+	if len(urls) == 0 || urls[0] != "https://github.com/spf13/cobra" {
+		t.Fatalf("want github.com first when boosted, got %v", urls)
+	}
+}
@@ -5,9 +5,12 @@ go 1.19
 require (
 	github.com/PuerkitoBio/goquery v1.8.1
 	github.com/davemolk/fuzzyHelpers v0.1.0
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/net v0.7.0
+	golang.org/x/term v0.5.0
 )
 
 require (
 	github.com/andybalholm/cascadia v1.3.1 // indirect
-	golang.org/x/net v0.7.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
 )
@@ -0,0 +1,309 @@
+package search
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyRun is a single search run as stored in the history.
+// Results counts every result the run found, and New only those
+// not already stored for the same query.
+type historyRun struct {
+	Time    time.Time `json:"time"`
+	Queries []string  `json:"queries"`
+	Engines []string  `json:"engines"`
+	Results int       `json:"results"`
+	New     int       `json:"new"`
+}
+
+// historyHit is a stored result along with when it was first seen.
+type historyHit struct {
+	result
+	Time time.Time `json:"time"`
+}
+
+// The history is a bolt database with a bucket of runs, in order,
+// a bucket of hits keyed by query and url, and a full-text index
+// of the words in each hit's title, url, and blurb, keyed by word
+// and then hit, so finding a word is a prefix scan.
+var (
+	runsBucket  = []byte("runs")
+	hitsBucket  = []byte("hits")
+	wordsBucket = []byte("words")
+)
+
+// history stores search runs and their results in a database file.
+type history struct {
+	path string
+}
+
+// historyPath returns $SEARCH_HISTORY, or history.db in the user's
+// config directory.
+func historyPath() (string, error) {
+	if p := os.Getenv("SEARCH_HISTORY"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find history: %w", err)
+	}
+	return filepath.Join(dir, "search", "history.db"), nil
+}
+
+// view runs fn with the history open for reading. A missing file
+// is an empty history, and fn isn't called.
+func (h *history) view(fn func(tx *bolt.Tx) error) error {
+	_, err := os.Stat(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	// wait a little for a running search to finish saving
+	db, err := bolt.Open(h.path, 0o644, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("unable to read history: %w", err)
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(runsBucket) == nil {
+			return nil
+		}
+		return fn(tx)
+	})
+}
+
+// runs returns every stored run, oldest first.
+func (h *history) runs() ([]historyRun, error) {
+	var runs []historyRun
+	err := h.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).ForEach(func(_, v []byte) error {
+			var run historyRun
+			err := json.Unmarshal(v, &run)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	return runs, err
+}
+
+// save stores run along with the results whose query and url
+// haven't been stored before, indexing their words.
+func (h *history) save(run historyRun, results []result) error {
+	err := os.MkdirAll(filepath.Dir(h.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to save history: %w", err)
+	}
+	db, err := bolt.Open(h.path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("unable to save history: %w", err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := make([]*bolt.Bucket, 3)
+		for i, name := range [][]byte{runsBucket, hitsBucket, wordsBucket} {
+			buckets[i], err = tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		runs, hits, words := buckets[0], buckets[1], buckets[2]
+		run.Results = len(results)
+		for _, r := range results {
+			key := []byte(r.Query + "\x00" + r.URL)
+			if r.URL == "" || hits.Get(key) != nil {
+				continue
+			}
+			r.Check = nil
+			r.Text = ""
+			v, err := json.Marshal(historyHit{result: r, Time: run.Time})
+			if err != nil {
+				return err
+			}
+			err = hits.Put(key, v)
+			if err != nil {
+				return err
+			}
+			for _, w := range historyWords(r.Title + " " + r.URL + " " + r.Blurb) {
+				err = words.Put(append([]byte(w+"\x00"), key...), nil)
+				if err != nil {
+					return err
+				}
+			}
+			run.New++
+		}
+		id, err := runs.NextSequence()
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		return runs.Put(binary.BigEndian.AppendUint64(nil, id), v)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save history: %w", err)
+	}
+	return db.Close()
+}
+
+// historyWords splits text into the lowercase words the history
+// indexes, once each.
+func historyWords(text string) []string {
+	split := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}
+	seen := make(map[string]bool)
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), split) {
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// grep returns the stored hits whose title, url, or blurb has every
+// word, ignoring case, newest first. A word ending in * matches any
+// word it starts.
+func (h *history) grep(terms []string) ([]historyHit, error) {
+	var found []historyHit
+	err := h.view(func(tx *bolt.Tx) error {
+		var keys map[string]bool
+		c := tx.Bucket(wordsBucket).Cursor()
+		for _, term := range terms {
+			prefix := strings.HasSuffix(term, "*")
+			words := historyWords(strings.TrimSuffix(term, "*"))
+			for i, w := range words {
+				// only the last word of a term like go.dev* is a prefix
+				seek := []byte(w + "\x00")
+				if prefix && i == len(words)-1 {
+					seek = []byte(w)
+				}
+				matches := make(map[string]bool)
+				for k, _ := c.Seek(seek); k != nil && bytes.HasPrefix(k, seek); k, _ = c.Next() {
+					if i := bytes.IndexByte(k, 0); i >= 0 && (keys == nil || keys[string(k[i+1:])]) {
+						matches[string(k[i+1:])] = true
+					}
+				}
+				keys = matches
+			}
+		}
+		hits := tx.Bucket(hitsBucket)
+		for key := range keys {
+			var hit historyHit
+			err := json.Unmarshal(hits.Get([]byte(key)), &hit)
+			if err != nil {
+				return err
+			}
+			found = append(found, hit)
+		}
+		return nil
+	})
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].Time.Equal(found[j].Time) {
+			return found[i].Time.After(found[j].Time)
+		}
+		return found[i].Query+found[i].URL < found[j].Query+found[j].URL
+	})
+	return found, err
+}
+
+// record saves the results of the run to the history, if it's on.
+func (s *searcher) record(results []result) {
+	if s.history == nil {
+		return
+	}
+	if s.history.path == "" {
+		path, err := historyPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v (use -history=false to stop saving runs)\n", err)
+			return
+		}
+		s.history.path = path
+	}
+	run := historyRun{
+		Time: time.Now(),
+	}
+	for _, term := range s.queryTerms() {
+		run.Queries = append(run.Queries, strings.ReplaceAll(s.format(term), "+", " "))
+	}
+	for _, e := range s.engines() {
+		run.Engines = append(run.Engines, e.name)
+	}
+	err := s.history.save(run, results)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// runHistory lists past searches, or with grep, the stored results
+// matching some text. -f reads a history file other than the usual
+// one.
+func runHistory(args []string, out io.Writer) error {
+	fset := flag.NewFlagSet("history", flag.ContinueOnError)
+	file := fset.String("f", "", "history file")
+	fset.SetOutput(out)
+	err := fset.Parse(args)
+	if err != nil {
+		return err
+	}
+	h := &history{path: *file}
+	if h.path == "" {
+		h.path, err = historyPath()
+		if err != nil {
+			return err
+		}
+	}
+	args = fset.Args()
+	if len(args) > 0 && args[0] != "grep" {
+		return fmt.Errorf("unknown history command %q (try grep)", args[0])
+	}
+	if len(args) > 0 {
+		if len(args) == 1 {
+			return errors.New("usage: search history grep <text>")
+		}
+		hits, err := h.grep(strings.Fields(strings.Join(args[1:], " ")))
+		if err != nil {
+			return err
+		}
+		for _, hit := range hits {
+			fmt.Fprintf(out, "%s [%s]\n%s\n", hit.Title, hit.Engine, hit.URL)
+			if hit.Blurb != "" {
+				fmt.Fprintln(out, hit.Blurb)
+			}
+			fmt.Fprintf(out, "seen %s searching %q\n\n", hit.Time.Local().Format("2006-01-02 15:04"), hit.Query)
+		}
+		return nil
+	}
+	runs, err := h.runs()
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		fmt.Fprintf(out, "%s  %s  [%s]  %d results, %d new\n",
+			run.Time.Local().Format("2006-01-02 15:04"),
+			strings.Join(run.Queries, "; "),
+			strings.Join(run.Engines, ", "),
+			run.Results,
+			run.New,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package search_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "nested", "history.db")
+	first := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	goDev := search.Result{Query: "golang", Engine: "brave", Rank: 1, Title: "The Go Programming Language", URL: "https://go.dev/", Blurb: "Build simple, secure, scalable systems"}
+	cobra := search.Result{Query: "golang", Engine: "brave", Rank: 2, Title: "spf13/cobra", URL: "https://github.com/spf13/cobra", Blurb: "A Commander for modern Go CLI interactions"}
+	err := search.SaveHistory(path, first, []string{"golang"}, []search.Result{goDev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = search.SaveHistory(path, first.Add(time.Hour), []string{"golang"}, []search.Result{goDev, cobra})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = search.RunHistory([]string{"-f", path}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 runs, got %q", out.String())
+	}
+	if !strings.Contains(lines[0], "golang  [brave]  1 results, 1 new") {
+		t.Errorf("first run: got %q", lines[0])
+	}
+	// go.dev was already stored for golang, so only cobra is new
+	if !strings.Contains(lines[1], "2 results, 1 new") {
+		t.Errorf("second run: got %q", lines[1])
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"COMMANDER", []string{"https://github.com/spf13/cobra"}},
+		{"go secure", []string{"https://go.dev/"}},
+		{"go", []string{"https://github.com/spf13/cobra", "https://go.dev/"}},
+		{"scal*", []string{"https://go.dev/"}},
+		{"github.com/spf13", []string{"https://github.com/spf13/cobra"}},
+		{"secure rust", nil},
+		{"rust", nil},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		err = search.RunHistory([]string{"-f", path, "grep", tt.text}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(out.String(), "\n") {
+			if strings.HasPrefix(line, "https://") {
+				got = append(got, line)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("grep %q: want %v, got %v", tt.text, tt.want, got)
+		}
+	}
+}
+
+func TestHistoryEmpty(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	err := search.RunHistory([]string{"-f", filepath.Join(t.TempDir(), "missing.db")}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("want no output, got %q", out.String())
+	}
+	err = search.RunHistory([]string{"-f", "x", "list"}, &out)
+	if err == nil {
+		t.Fatal("want error for unknown command")
+	}
+}
+
+func TestHistoryDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	t.Setenv("SEARCH_HISTORY", path)
+	results := []search.Result{{Query: "golang", Engine: "brave", Rank: 1, URL: "https://go.dev/"}}
+
+	s, err := search.NewSearcher(search.FromArgs([]string{"-s", "golang", "-n", "-history=false"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record(results)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want no history with -history=false, got %v", err)
+	}
+
+	s, err = search.NewSearcher(search.FromArgs([]string{"-s", "golang", "-n"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record(results)
+	var out bytes.Buffer
+	err = search.RunHistory([]string{"-f", path}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1 results, 1 new") {
+		t.Errorf("want the run saved by default, got %q", out.String())
+	}
+}
@@ -0,0 +1,170 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hookAttempts is how many times a webhook or command is tried
+// before giving up on it.
+const hookAttempts = 3
+
+// webhookBatch is the most results sent in a single webhook post.
+const webhookBatch = 100
+
+// webhookBody is the json posted to -webhook.
+type webhookBody struct {
+	Time    time.Time `json:"time"`
+	Results []result  `json:"results"`
+}
+
+// deliver forwards results to -webhook, in batches, and runs -exec
+// once per result. Each delivery is retried with backoff; the ones
+// that still fail are returned, and don't stop the others.
+func (s *searcher) deliver(ctx context.Context, results []result) []error {
+	var errs []error
+	if s.webhook != "" {
+		now := time.Now()
+		for i := 0; i < len(results); i += webhookBatch {
+			end := i + webhookBatch
+			if end > len(results) {
+				end = len(results)
+			}
+			body := webhookBody{Time: now, Results: results[i:end]}
+			err := s.retry(ctx, func() error {
+				return s.post(ctx, body)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("webhook: %w", err))
+			}
+		}
+	}
+	if len(s.exec) > 0 {
+		for _, r := range results {
+			r := r
+			err := s.retry(ctx, func() error {
+				return s.execute(ctx, r)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("exec for %s: %w", r.URL, err))
+			}
+		}
+	}
+	return errs
+}
+
+// retry calls fn until it succeeds, fails permanently, or has been
+// tried hookAttempts times, doubling the wait after each failure.
+func (s *searcher) retry(ctx context.Context, fn func() error) error {
+	wait := s.retryDelay
+	var err error
+	for i := 0; i < hookAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wait *= 2
+		}
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(permanentError); ok {
+			return err
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", hookAttempts, err)
+}
+
+// permanentError is a failure that trying again won't fix.
+type permanentError struct {
+	error
+}
+
+// post sends body to the webhook, through the client that verifies
+// certificates since results can be private. Client errors other
+// than 408 and 429 aren't retried.
+func (s *searcher) post(ctx context.Context, body webhookBody) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return permanentError{err}
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.timeout)*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhook, bytes.NewReader(b))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.verified.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("HTTP response: %d for %s", resp.StatusCode, s.webhook)
+	default:
+		return permanentError{fmt.Errorf("HTTP response: %d for %s", resp.StatusCode, s.webhook)}
+	}
+}
+
+// execute runs the -exec command for r. {url}, {title}, {blurb},
+// {engine}, {query}, and {rank} in its arguments are replaced with
+// r's fields, which are also set as SEARCH_URL, SEARCH_TITLE, and
+// so on in its environment. The command is run directly, not by a
+// shell, and never replaced, so fields can't pick what runs. Its
+// output goes to stderr, apart from the results.
+func (s *searcher) execute(ctx context.Context, r result) error {
+	fields := map[string]string{
+		"url":    r.URL,
+		"title":  r.Title,
+		"blurb":  r.Blurb,
+		"engine": r.Engine,
+		"query":  r.Query,
+		"rank":   strconv.Itoa(r.Rank),
+	}
+	var pairs []string
+	env := os.Environ()
+	for k, v := range fields {
+		pairs = append(pairs, "{"+k+"}", v)
+		env = append(env, "SEARCH_"+strings.ToUpper(k)+"="+v)
+	}
+	replace := strings.NewReplacer(pairs...)
+	args := make([]string, len(s.exec)-1)
+	for i, arg := range s.exec[1:] {
+		args[i] = replace.Replace(arg)
+	}
+	cmd := exec.CommandContext(ctx, s.exec[0], args...)
+	cmd.Env = env
+	cmd.Stdout = os.Stderr
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		var notFound *exec.Error
+		if errors.As(err, &notFound) {
+			return permanentError{err}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package search_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+func hookResults(n int) []search.Result {
+	var results []search.Result
+	for i := 1; i <= n; i++ {
+		results = append(results, search.Result{
+			Query:  "golang",
+			Engine: "brave",
+			Rank:   i,
+			Title:  "result",
+			URL:    "https://example.com/" + strings.Repeat("x", i),
+		})
+	}
+	return results
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var attempts int
+	var batches []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// fail the first attempt of each batch
+		if attempts%2 == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var body struct {
+			Results []search.Result `json:"results"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Error(err)
+		}
+		batches = append(batches, len(body.Results))
+	}))
+	defer ts.Close()
+
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "golang", "-n", "-webhook", ts.URL}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := s.Deliver(hookResults(150))
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if attempts != 4 || len(batches) != 2 || batches[0] != 100 || batches[1] != 50 {
+		t.Fatalf("want batches of 100 and 50 after 4 attempts, got %v after %d", batches, attempts)
+	}
+}
+
+func TestWebhookFailure(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status   int
+		attempts int
+	}{
+		{http.StatusInternalServerError, 3},
+		{http.StatusTooManyRequests, 3},
+		// not worth retrying
+		{http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		var attempts int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			w.WriteHeader(tt.status)
+		}))
+		s, err := search.NewSearcher(
+			search.FromArgs([]string{"-s", "golang", "-n", "-webhook", ts.URL}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := s.Deliver(hookResults(1))
+		ts.Close()
+		if len(errs) != 1 {
+			t.Fatalf("%d: want 1 error, got %v", tt.status, errs)
+		}
+		if attempts != tt.attempts {
+			t.Errorf("%d: want %d attempts, got %d", tt.status, tt.attempts, attempts)
+		}
+	}
+}
+
+func TestWebhookVerifiesCertificates(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var delivered int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		delivered++
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	tests := []struct {
+		opts []search.Option
+		errs int
+	}{
+		{nil, 1},
+		{[]search.Option{search.WithVerifiedClient(ts.Client())}, 0},
+	}
+	for _, tt := range tests {
+		s, err := search.NewSearcher(append(tt.opts,
+			search.FromArgs([]string{"-s", "golang", "-n", "-webhook", ts.URL}),
+		)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs := s.Deliver(hookResults(1)); len(errs) != tt.errs {
+			t.Errorf("want %d errors, got %v", tt.errs, errs)
+		}
+	}
+	if delivered != 1 {
+		t.Errorf("want 1 delivery, to the trusted client, got %d", delivered)
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	log := filepath.Join(t.TempDir(), "log")
+	// fail the first run, which leaves no log yet
+	script := `test -f ` + log + ` || { touch ` + log + `; exit 1; }; echo "$SEARCH_ENGINE $SEARCH_RANK $1" >> ` + log
+	var out bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs([]string{"-s", "golang", "-n", "-exec", `sh -c '` + script + `' sh {url}`}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := s.Deliver(hookResults(2))
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "brave 1 https://example.com/x\nbrave 2 https://example.com/xx\n"
+	if string(b) != want {
+		t.Fatalf("want %q, got %q", want, b)
+	}
+}
+
+func TestExecFailure(t *testing.T) {
+	t.Parallel()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "golang", "-n", "-exec", "no-such-command-for-search {url}"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := s.Deliver(hookResults(2))
+	// one failure per result, and the search goes on
+	if len(errs) != 2 {
+		t.Fatalf("want 2 errors, got %v", errs)
+	}
+}
+
+func TestExecCommandNotReplaced(t *testing.T) {
+	t.Parallel()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "golang", "-n", "-exec", "{engine} {url}"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a result can't choose the command, even one that exists
+	errs := s.Deliver([]search.Result{{Engine: "true", URL: "https://example.com/"}})
+	if len(errs) != 1 {
+		t.Fatalf("want 1 error, got %v", errs)
+	}
+}
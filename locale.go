@@ -0,0 +1,151 @@
+package search
+
+import (
+	"net/url"
+	"strings"
+)
+
+// safeLevels are the -safe settings, from least to most filtered.
+var safeLevels = []string{"off", "moderate", "strict"}
+
+// locale is the region, language, and safe search setting sent to
+// each engine. Any of them may be empty, leaving it up to the engine.
+type locale struct {
+	region string
+	lang   string
+	safe   string
+}
+
+// regionLangs guesses a language for regions where it isn't the
+// region code, so -region alone is enough.
+var regionLangs = map[string]string{
+	"ar": "es", "at": "de", "au": "en", "br": "pt", "ca": "en",
+	"ch": "de", "cl": "es", "cn": "zh", "gb": "en", "ie": "en",
+	"in": "en", "jp": "ja", "kr": "ko", "mx": "es", "nz": "en",
+	"se": "sv", "tw": "zh", "us": "en", "za": "en",
+}
+
+// language returns -lang, or a guess from -region.
+func (l locale) language() string {
+	if l.lang != "" || l.region == "" {
+		return l.lang
+	}
+	if lang, ok := regionLangs[l.region]; ok {
+		return lang
+	}
+	return l.region
+}
+
+// tag returns a language tag like en-US, or just en without a region.
+func (l locale) tag() string {
+	lang := l.language()
+	if lang == "" || l.region == "" {
+		return lang
+	}
+	return lang + "-" + strings.ToUpper(l.region)
+}
+
+// acceptLanguage is the Accept-Language header for l, or "" to keep
+// the browser's.
+func (l locale) acceptLanguage() string {
+	lang := l.language()
+	if lang == "" {
+		return ""
+	}
+	if tag := l.tag(); tag != lang {
+		return tag + "," + lang + ";q=0.9"
+	}
+	return lang
+}
+
+// pick returns the value for l.safe from off, moderate, and strict.
+func (l locale) pick(off, moderate, strict string) string {
+	switch l.safe {
+	case "off":
+		return off
+	case "moderate":
+		return moderate
+	case "strict":
+		return strict
+	}
+	return ""
+}
+
+// params builds the query string for whichever of the settings are
+// set, leaving out empty values.
+func params(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		b.WriteString("&" + pairs[i] + "=" + url.QueryEscape(pairs[i+1]))
+	}
+	return b.String()
+}
+
+func bingLocale(l locale) string {
+	// a market is a language and region, so a bare language only
+	// sets the interface language
+	var mkt string
+	if l.region != "" {
+		mkt = l.tag()
+	}
+	return params("mkt", mkt, "setlang", l.language(), "adlt", l.safe)
+}
+
+func bingAPILocale(l locale) string {
+	var mkt string
+	if l.region != "" {
+		mkt = l.tag()
+	}
+	return params("mkt", mkt, "setLang", l.language(), "safeSearch", l.pick("Off", "Moderate", "Strict"))
+}
+
+func braveLocale(l locale) string {
+	return params("country", l.region, "lang", l.language(), "safesearch", l.safe)
+}
+
+func braveAPILocale(l locale) string {
+	return params("country", l.region, "search_lang", l.language(), "safesearch", l.safe)
+}
+
+func duckLocale(l locale) string {
+	var kl string
+	if l.region != "" {
+		region := l.region
+		if region == "gb" {
+			region = "uk"
+		}
+		kl = region + "-" + l.language()
+	}
+	return params("kl", kl, "kp", l.pick("-2", "-1", "1"))
+}
+
+func mojeekLocale(l locale) string {
+	return params("arc", l.region, "lb", l.language(), "safe", l.pick("0", "1", "1"))
+}
+
+func qwantLocale(l locale) string {
+	var loc string
+	if l.region != "" {
+		loc = l.language() + "_" + strings.ToUpper(l.region)
+	}
+	return params("locale", loc, "s", l.pick("0", "1", "2"))
+}
+
+func searxngLocale(l locale) string {
+	return params("language", l.tag(), "safesearch", l.pick("0", "1", "2"))
+}
+
+func startpageLocale(l locale) string {
+	return params("qadf", l.pick("none", "moderate", "heavy"))
+}
+
+func yahooLocale(l locale) string {
+	var vl string
+	if lang := l.language(); lang != "" {
+		vl = "lang_" + lang
+	}
+	return params("vl", vl, "vm", l.pick("p", "i", "r"))
+}
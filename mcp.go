@@ -0,0 +1,350 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mcpVersions are the Model Context Protocol revisions we speak,
+// newest first.
+var mcpVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// json-rpc error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// maxFetchLength is the default cap on text returned by fetch_result.
+const maxFetchLength = 20000
+
+// mcpServer speaks the Model Context Protocol over stdio, exposing
+// web_search and fetch_result tools backed by the search fan-out.
+type mcpServer struct {
+	*server
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewMCP returns an MCP server. args and opts work as they do for
+// NewServer, and timeout bounds each tool call.
+func NewMCP(args []string, timeout time.Duration, opts ...option) (*mcpServer, error) {
+	sv, err := NewServer(args, timeout, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mcpServer{server: sv}, nil
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// Serve reads newline-delimited json-rpc messages from in and writes
+// responses to out until in is exhausted. Tool calls run
+// concurrently, sharing the server's limit on requests.
+func (m *mcpServer) Serve(in io.Reader, out io.Writer) error {
+	m.out = out
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	scan := bufio.NewScanner(in)
+	scan.Buffer(make([]byte, 64*1024), 10<<20)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" {
+			continue
+		}
+		var req rpcRequest
+		err := json.Unmarshal([]byte(line), &req)
+		if err != nil {
+			m.write(rpcResponse{ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.handle(req)
+		}()
+	}
+	return scan.Err()
+}
+
+// handle answers a single request. Notifications get no response.
+func (m *mcpServer) handle(req rpcRequest) {
+	result, rerr := m.dispatch(req)
+	if len(req.ID) == 0 {
+		return
+	}
+	resp := rpcResponse{ID: req.ID, Result: result, Error: rerr}
+	if rerr == nil && result == nil {
+		resp.Result = struct{}{}
+	}
+	m.write(resp)
+}
+
+func (m *mcpServer) write(resp rpcResponse) {
+	resp.JSONRPC = "2.0"
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	json.NewEncoder(m.out).Encode(resp)
+}
+
+func (m *mcpServer) dispatch(req rpcRequest) (any, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "jsonrpc must be 2.0"}
+	}
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := mcpVersions[0]
+		if contains(mcpVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    "search",
+				"version": "0.1.0",
+			},
+		}, nil
+	case "ping":
+		return nil, nil
+	case "tools/list":
+		return map[string]any{"tools": mcpTools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		err := json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		switch params.Name {
+		case "web_search":
+			return m.webSearch(params.Arguments), nil
+		case "fetch_result":
+			return m.fetchResult(params.Arguments), nil
+		default:
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool " + params.Name}
+		}
+	default:
+		if strings.HasPrefix(req.Method, "notifications/") {
+			return nil, nil
+		}
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// mcpTools describes the tools we offer.
+func mcpTools() []map[string]any {
+	return []map[string]any{
+		{
+			"name":        "web_search",
+			"description": "Search the web with several search engines at once. Returns the title, url, snippet, and engine of each result.",
+			"inputSchema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "what to search for",
+					},
+					"engines": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string", "enum": engineNames},
+						"description": "search engines to query (defaults to the server's)",
+					},
+					"max_results": map[string]any{
+						"type":        "integer",
+						"minimum":     1,
+						"description": "maximum number of results to return",
+					},
+					"site": map[string]any{
+						"type":        "string",
+						"description": "only return results from this domain, e.g. go.dev",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+		{
+			"name":        "fetch_result",
+			"description": "Download a web page, such as a search result, and return its readable text.",
+			"inputSchema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url": map[string]any{
+						"type":        "string",
+						"description": "the page to read",
+					},
+					"max_length": map[string]any{
+						"type":        "integer",
+						"minimum":     1,
+						"description": fmt.Sprintf("maximum characters of text to return (default %d)", maxFetchLength),
+					},
+				},
+				"required": []string{"url"},
+			},
+		},
+	}
+}
+
+// toolResult builds a tools/call result with text for the model and,
+// when v is set, the same data as structured content.
+func toolResult(text string, v any, isError bool) map[string]any {
+	res := map[string]any{
+		"content": []map[string]any{
+			{"type": "text", "text": text},
+		},
+		"isError": isError,
+	}
+	if v != nil {
+		res["structuredContent"] = v
+	}
+	return res
+}
+
+func (m *mcpServer) webSearch(raw json.RawMessage) map[string]any {
+	var args struct {
+		Query      string   `json:"query"`
+		Engines    []string `json:"engines"`
+		MaxResults int      `json:"max_results"`
+		Site       string   `json:"site"`
+	}
+	err := json.Unmarshal(raw, &args)
+	if err != nil {
+		return toolResult("invalid arguments: "+err.Error(), nil, true)
+	}
+	params := url.Values{"q": {strings.TrimSpace(args.Query)}}
+	site := strings.TrimPrefix(strings.TrimPrefix(args.Site, "https://"), "http://")
+	site = strings.TrimSuffix(site, "/")
+	if site != "" {
+		params.Set("q", params.Get("q")+" site:"+site)
+	}
+	if len(args.Engines) > 0 {
+		params.Set("engines", strings.Join(args.Engines, ","))
+	}
+	s, err := m.searcher(params)
+	if err != nil {
+		return toolResult(err.Error(), nil, true)
+	}
+	_, results, failures := m.run(context.Background(), s)
+
+	var kept []result
+	for _, r := range results {
+		if site != "" && !onSite(r.URL, site) {
+			continue
+		}
+		kept = append(kept, r)
+		if args.MaxResults > 0 && len(kept) == args.MaxResults {
+			break
+		}
+	}
+	var b strings.Builder
+	for i, r := range kept {
+		fmt.Fprintf(&b, "%d. %s [%s]\n%s\n%s\n\n", i+1, r.Title, r.Engine, r.URL, r.Blurb)
+	}
+	for engine, msg := range failures {
+		fmt.Fprintf(&b, "%s failed: %s\n", engine, msg)
+	}
+	if len(kept) == 0 {
+		b.WriteString("no results\n")
+	}
+	if kept == nil {
+		kept = []result{}
+	}
+	return toolResult(strings.TrimSpace(b.String()), map[string]any{
+		"query":   args.Query,
+		"results": kept,
+		"errors":  failures,
+	}, false)
+}
+
+// onSite reports whether u is on domain or one of its subdomains.
+func onSite(u, domain string) bool {
+	p, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(p.Hostname())
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func (m *mcpServer) fetchResult(raw json.RawMessage) map[string]any {
+	var args struct {
+		URL       string `json:"url"`
+		MaxLength int    `json:"max_length"`
+	}
+	err := json.Unmarshal(raw, &args)
+	if err != nil {
+		return toolResult("invalid arguments: "+err.Error(), nil, true)
+	}
+	if args.MaxLength <= 0 {
+		args.MaxLength = maxFetchLength
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	p, err := m.base.readPage(ctx, args.URL)
+	if err != nil {
+		return toolResult(err.Error(), nil, true)
+	}
+	p.Text = truncate(p.Text, args.MaxLength)
+	text := p.Text
+	if p.Title != "" {
+		text = p.Title + "\n\n" + text
+	}
+	return toolResult(text, p, false)
+}
+
+// runMCP serves MCP over stdin and stdout. -rt bounds each tool
+// call, and any other flags are passed to NewMCP.
+func runMCP(args []string, opts ...option) error {
+	fset := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	rt := fset.Int("rt", 10000, "timeout for each tool call, in ms")
+	own, rest := splitFlags(args, "rt")
+	err := fset.Parse(own)
+	if err != nil {
+		return err
+	}
+	if *rt <= 0 {
+		return fmt.Errorf("rt must be greater than 0")
+	}
+	m, err := NewMCP(rest, time.Duration(*rt)*time.Millisecond, opts...)
+	if err != nil {
+		return err
+	}
+	return m.Serve(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,186 @@
+package search_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type toolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError           bool            `json:"isError"`
+	StructuredContent json.RawMessage `json:"structuredContent"`
+}
+
+// runMCP sends each request to a new MCP server and returns the
+// responses by id.
+func runMCP(t *testing.T, opts []search.Option, requests ...string) map[int]rpcResponse {
+	t.Helper()
+	m, err := search.NewMCP([]string{"-engines", "brave,mojeek"}, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	err = m.Serve(strings.NewReader(strings.Join(requests, "\n")), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := make(map[int]rpcResponse)
+	scan := bufio.NewScanner(strings.NewReader(out.String()))
+	for scan.Scan() {
+		var resp rpcResponse
+		err := json.Unmarshal(scan.Bytes(), &resp)
+		if err != nil {
+			t.Fatalf("bad response %s: %v", scan.Text(), err)
+		}
+		responses[resp.ID] = resp
+	}
+	return responses
+}
+
+func callTool(id int, name string, args any) string {
+	b, _ := json.Marshal(args)
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, id, name, b)
+}
+
+func TestMCPHandshake(t *testing.T) {
+	t.Parallel()
+	responses := runMCP(t, nil,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"bogus"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"ping"}`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("got %d responses want 4 (notifications get none)", len(responses))
+	}
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(responses[1].Result, &init)
+	if init.ProtocolVersion != "2024-11-05" {
+		t.Errorf("got protocol version %q", init.ProtocolVersion)
+	}
+	var list struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	json.Unmarshal(responses[2].Result, &list)
+	if len(list.Tools) != 2 || list.Tools[0].Name != "web_search" || list.Tools[1].Name != "fetch_result" {
+		t.Errorf("got tools %+v", list.Tools)
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != -32601 {
+		t.Errorf("want method not found, got %+v", responses[3])
+	}
+	if responses[4].Error != nil {
+		t.Errorf("ping failed: %+v", responses[4].Error)
+	}
+}
+
+func TestMCPWebSearch(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	responses := runMCP(t, opts,
+		callTool(1, "web_search", map[string]any{"query": "golang", "site": "pkg.go.dev"}),
+		callTool(2, "web_search", map[string]any{"query": "golang", "engines": []string{"brave"}, "max_results": 1}),
+		callTool(3, "web_search", map[string]any{"query": "golang", "engines": []string{"altavista"}}),
+	)
+
+	var res toolResult
+	json.Unmarshal(responses[1].Result, &res)
+	var structured struct {
+		Results []struct {
+			URL string `json:"url"`
+		} `json:"results"`
+	}
+	json.Unmarshal(res.StructuredContent, &structured)
+	if res.IsError || len(structured.Results) != 1 || structured.Results[0].URL != "https://pkg.go.dev/flag" {
+		t.Errorf("site filter: got %+v", structured)
+	}
+
+	res = toolResult{}
+	json.Unmarshal(responses[2].Result, &res)
+	if res.IsError || !strings.HasPrefix(res.Content[0].Text, "1. ") || strings.Contains(res.Content[0].Text, "2. ") {
+		t.Errorf("max results: got %q", res.Content[0].Text)
+	}
+
+	res = toolResult{}
+	json.Unmarshal(responses[3].Result, &res)
+	if !res.IsError {
+		t.Error("want tool error for unknown engine")
+	}
+}
+
+func TestMCPFetchResult(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Gophers</title><script>var x = 1;</script></head>
+<body><h1>All about gophers</h1><p>Gophers   are <b>great</b>.</p><p>They dig.</p></body></html>`)
+	}))
+	defer ts.Close()
+	responses := runMCP(t, nil,
+		callTool(1, "fetch_result", map[string]any{"url": ts.URL + "/old"}),
+		callTool(2, "fetch_result", map[string]any{"url": "ftp://example.com"}),
+	)
+	var res toolResult
+	json.Unmarshal(responses[1].Result, &res)
+	want := "Gophers\n\nAll about gophers\nGophers are great.\nThey dig."
+	if res.IsError || res.Content[0].Text != want {
+		t.Errorf("got %q want %q", res.Content[0].Text, want)
+	}
+	var p struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(res.StructuredContent, &p)
+	if p.URL != ts.URL+"/article" {
+		t.Errorf("got final url %q", p.URL)
+	}
+
+	res = toolResult{}
+	json.Unmarshal(responses[2].Result, &res)
+	if !res.IsError {
+		t.Error("want tool error for non-web url")
+	}
+}
+
+func TestMCPFetchResultMaxLength(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>日本語のテキストです</p></body></html>`)
+	}))
+	defer ts.Close()
+	responses := runMCP(t, nil,
+		callTool(1, "fetch_result", map[string]any{"url": ts.URL, "max_length": 6}),
+	)
+	var res toolResult
+	json.Unmarshal(responses[1].Result, &res)
+	// max_length counts characters, however wide they are on screen
+	if want := "日本語のテキ"; res.IsError || res.Content[0].Text != want {
+		t.Errorf("got %q want %q", res.Content[0].Text, want)
+	}
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davemolk/fuzzyHelpers"
+)
+
+// ddgSuggest is duck duck go's autocomplete endpoint, which already
+// answers in the OpenSearch suggestions format.
+const ddgSuggest = "https://duckduckgo.com/ac/?type=list&q="
+
+// openSearch serves an OpenSearch description, an html results page,
+// and search suggestions, so the meta-search can be registered as a
+// browser search engine.
+type openSearch struct {
+	*server
+	suggestURL string
+}
+
+// NewOpenSearch returns an http.Handler serving /opensearch.xml,
+// /suggest, and html results at /. args and opts work as they do
+// for NewServer, and results are always merged.
+func NewOpenSearch(args []string, timeout time.Duration, opts ...option) (*openSearch, error) {
+	sv, err := NewServer(append([]string{"-merge"}, args...), timeout, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &openSearch{
+		server:     sv,
+		suggestURL: ddgSuggest,
+	}, nil
+}
+
+func (o *openSearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		o.handleResults(w, r)
+	case "/opensearch.xml":
+		o.handleDescription(w, r)
+	case "/suggest":
+		o.handleSuggest(w, r)
+	case "/healthz":
+		o.handleHealth(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// openSearchURL is a Url element of an OpenSearch description.
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Method   string `xml:"method,attr,omitempty"`
+	Rel      string `xml:"rel,attr,omitempty"`
+	Template string `xml:"template,attr"`
+}
+
+// openSearchDescription is an OpenSearch 1.1 description document.
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+// handleDescription serves the OpenSearch description, pointing
+// back at whatever host the browser used to reach us.
+func (o *openSearch) handleDescription(w http.ResponseWriter, r *http.Request) {
+	base := "http://" + r.Host
+	if r.TLS != nil {
+		base = "https://" + r.Host
+	}
+	var names []string
+	for _, e := range o.engines() {
+		names = append(names, e.name)
+	}
+	desc := openSearchDescription{
+		ShortName:     "search",
+		Description:   "Search " + strings.Join(names, ", ") + " at once",
+		InputEncoding: "UTF-8",
+		URLs: []openSearchURL{
+			{Type: "text/html", Method: "get", Template: base + "/?q={searchTerms}"},
+			{Type: "application/x-suggestions+json", Method: "get", Template: base + "/suggest?q={searchTerms}"},
+			{Type: "application/opensearchdescription+xml", Rel: "self", Template: base + "/opensearch.xml"},
+		},
+	}
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml")
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(desc)
+}
+
+var resultsPage = template.Must(template.New("results").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Query}}{{.Query}} - {{end}}search</title>
+<link rel="search" type="application/opensearchdescription+xml" title="search" href="/opensearch.xml">
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; padding: 0 1em; }
+input[type=text] { width: 70%; padding: .4em; }
+.result { margin: 1.5em 0; }
+.result a { font-size: 1.1em; }
+.url { color: #1a7f37; font-size: .9em; word-break: break-all; }
+.engines { color: #666; font-size: .8em; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<form action="/" method="get">
+<input type="text" name="q" value="{{.Query}}" autofocus>
+<input type="submit" value="search">
+</form>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Query}}<p class="engines">{{len .Entries}} results</p>{{end}}
+{{range .Entries}}<div class="result">
+<a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a>
+<div class="url">{{.URL}}</div>
+<p>{{.Blurb}}</p>
+<div class="engines">{{range $i, $e := .Engines}}{{if $i}}, {{end}}{{$e}}{{end}}</div>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// handleResults serves a search form, along with merged results
+// from every engine when q is set.
+func (o *openSearch) handleResults(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Query   string
+		Entries []entry
+		Error   string
+	}{
+		Query: strings.TrimSpace(r.URL.Query().Get("q")),
+	}
+	if data.Query != "" {
+		s, err := o.searcher(url.Values{"q": {data.Query}})
+		if err != nil {
+			data.Error = err.Error()
+		} else {
+			_, results, _ := o.run(r.Context(), s)
+			data.Entries = s.rank(merge(results))
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	resultsPage.Execute(w, data)
+}
+
+// handleSuggest answers with OpenSearch suggestions for q, as
+// ["q", ["suggestion", ...]]. Suggestions come from duck duck go's
+// autocomplete and are empty if it can't be reached.
+func (o *openSearch) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	suggestions := []string{}
+	if q != "" {
+		suggestions = append(suggestions, o.suggest(r, q)...)
+	}
+	w.Header().Set("Content-Type", "application/x-suggestions+json")
+	json.NewEncoder(w).Encode([]any{q, suggestions})
+}
+
+func (o *openSearch) suggest(r *http.Request, q string) []string {
+	u := o.suggestURL + url.QueryEscape(q)
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u, nil)
+	if err != nil {
+		return nil
+	}
+	h := fuzzyHelpers.NewHeaders(
+		fuzzyHelpers.WithURL(u),
+	)
+	req.Header = h.Headers()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var body []json.RawMessage
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil || len(body) < 2 {
+		return nil
+	}
+	var suggestions []string
+	json.Unmarshal(body[1], &suggestions)
+	return suggestions
+}
+
+// runOpenSearch serves the OpenSearch endpoints until the listener
+// fails.
+func runOpenSearch(args []string, opts ...option) error {
+	return runHTTP("opensearch", args, func(args []string, timeout time.Duration) (http.Handler, error) {
+		return NewOpenSearch(args, timeout, opts...)
+	})
+}
@@ -0,0 +1,116 @@
+package search_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+func TestOpenSearchDescription(t *testing.T) {
+	t.Parallel()
+	o, err := search.NewOpenSearch([]string{"-engines", "brave,mojeek"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/opensearch.xml", nil)
+	req.Host = "localhost:8080"
+	o.ServeHTTP(rec, req)
+	var desc struct {
+		ShortName   string `xml:"ShortName"`
+		Description string `xml:"Description"`
+		URLs        []struct {
+			Type     string `xml:"type,attr"`
+			Template string `xml:"template,attr"`
+		} `xml:"Url"`
+	}
+	err = xml.NewDecoder(rec.Body).Decode(&desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Description != "Search brave, mojeek at once" {
+		t.Errorf("got description %q", desc.Description)
+	}
+	want := map[string]string{
+		"text/html":                             "http://localhost:8080/?q={searchTerms}",
+		"application/x-suggestions+json":        "http://localhost:8080/suggest?q={searchTerms}",
+		"application/opensearchdescription+xml": "http://localhost:8080/opensearch.xml",
+	}
+	got := make(map[string]string)
+	for _, u := range desc.URLs {
+		got[u.Type] = u.Template
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestOpenSearchResultsPage(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	o, err := search.NewOpenSearch([]string{"-engines", "brave,mojeek"}, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	o.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?q=golang+cli", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`value="golang cli"`,
+		"3 results",
+		`<a href="https://pkg.go.dev/flag">flag package - flag - Go Packages</a>`,
+		`<a href="https://github.com/spf13/cobra">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("results page missing %q", want)
+		}
+	}
+	if !strings.Contains(body, "brave, mojeek") && !strings.Contains(body, "mojeek, brave") {
+		t.Error("want go.dev merged across brave and mojeek")
+	}
+}
+
+func TestOpenSearchSuggest(t *testing.T) {
+	t.Parallel()
+	ac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		json.NewEncoder(w).Encode([]any{q, []string{q + " tutorial", q + " generics"}})
+	}))
+	defer ac.Close()
+	o, err := search.NewOpenSearch(nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.SetSuggestURL(ac.URL + "/?q=")
+	rec := httptest.NewRecorder()
+	o.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/suggest?q=golang", nil))
+	want := `["golang",["golang tutorial","golang generics"]]` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func TestOpenSearchSuggestUnreachable(t *testing.T) {
+	t.Parallel()
+	o, err := search.NewOpenSearch(nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.SetSuggestURL("http://127.0.0.1:0/?q=")
+	rec := httptest.NewRecorder()
+	o.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/suggest?q=golang", nil))
+	want := `["golang",[]]` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
@@ -0,0 +1,330 @@
+package search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the result fields csv and tsv output can include,
+// in their default order.
+var csvColumns = []string{"query", "engine", "rank", "title", "url", "blurb"}
+
+// verticalColumns are the fields of news, images, and videos, which
+// -columns can add.
+var verticalColumns = []string{"date", "source", "image", "thumbnail", "width", "height", "duration"}
+
+// resultWriter writes results in one of the -o formats.
+type resultWriter interface {
+	write(r result) error
+	// close writes anything buffered.
+	close() error
+}
+
+// resultWriter returns a writer for s.outputFormat.
+func (s *searcher) resultWriter() resultWriter {
+	switch s.outputFormat {
+	case "markdown":
+		return &reportWriter{s: s, render: markdownReport.Execute}
+	case "html":
+		return &reportWriter{s: s, render: htmlReport.Execute}
+	case "json":
+		return &jsonWriter{s: s}
+	case "bibtex":
+		return &bibtexWriter{s: s}
+	case "csv", "tsv":
+		w := csv.NewWriter(s.output)
+		if s.outputFormat == "tsv" {
+			w.Comma = '\t'
+		}
+		return newCSVWriter(w, s.columns)
+	default:
+		if s.tmpl != nil {
+			return &templateWriter{s: s, tmpl: s.tmpl}
+		}
+		return textWriter{s}
+	}
+}
+
+// write writes results in s.outputFormat as they arrive or, with -merge,
+// merged and ranked once they're all in. Reports do their own
+// merging, per query. It returns every result and how many were
+// written.
+func (s *searcher) write(results <-chan result) ([]result, int, error) {
+	w := s.resultWriter()
+	var all []result
+	var n int
+	if _, ok := w.(*reportWriter); s.merged && !ok {
+		for r := range results {
+			all = append(all, r)
+		}
+		for i, e := range s.rank(merge(all)) {
+			e.Engine = strings.Join(e.Engines, ", ")
+			e.Rank = i + 1
+			err := w.write(e.result)
+			if err != nil {
+				return all, n, err
+			}
+			n++
+		}
+		return all, n, w.close()
+	}
+	for r := range results {
+		all = append(all, r)
+		err := w.write(r)
+		if err != nil {
+			return all, n, err
+		}
+		n++
+	}
+	return all, n, w.close()
+}
+
+// textWriter is the usual human readable output.
+type textWriter struct {
+	s *searcher
+}
+
+func (t textWriter) write(r result) error {
+	t.s.print(r)
+	return nil
+}
+
+func (t textWriter) close() error {
+	return nil
+}
+
+// csvWriter writes a header and then a row per result, quoting
+// fields as needed.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVWriter(w *csv.Writer, columns []string) *csvWriter {
+	// errors surface on close
+	w.Write(columns)
+	return &csvWriter{w: w, columns: columns}
+}
+
+func (c *csvWriter) write(r result) error {
+	row := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		switch col {
+		case "query":
+			row[i] = r.Query
+		case "engine":
+			row[i] = r.Engine
+		case "rank":
+			row[i] = strconv.Itoa(r.Rank)
+		case "title":
+			row[i] = r.Title
+		case "url":
+			row[i] = r.URL
+		case "blurb":
+			row[i] = r.Blurb
+		case "date":
+			row[i] = r.Date
+		case "source":
+			row[i] = r.Source
+		case "image":
+			row[i] = r.Image
+		case "thumbnail":
+			row[i] = r.Thumbnail
+		case "width":
+			row[i] = strconv.Itoa(r.Width)
+		case "height":
+			row[i] = strconv.Itoa(r.Height)
+		case "duration":
+			row[i] = r.Duration
+		case "authors":
+			row[i] = strings.Join(r.Authors, "; ")
+		case "year":
+			if r.Year > 0 {
+				row[i] = strconv.Itoa(r.Year)
+			}
+		case "doi":
+			row[i] = r.DOI
+		case "venue":
+			row[i] = r.Venue
+		case "pdf":
+			row[i] = r.PDF
+		}
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter writes every result as a single json array.
+type jsonWriter struct {
+	s       *searcher
+	results []result
+}
+
+func (jw *jsonWriter) write(r result) error {
+	jw.results = append(jw.results, r)
+	return nil
+}
+
+func (jw *jsonWriter) close() error {
+	enc := json.NewEncoder(jw.s.output)
+	enc.SetIndent("", "  ")
+	results := jw.results
+	if results == nil {
+		results = []result{}
+	}
+	return enc.Encode(results)
+}
+
+// bibtexWriter writes results as BibTeX entries, once each: results
+// for the same paper from several engines, found by DOI or title,
+// are combined.
+type bibtexWriter struct {
+	s      *searcher
+	papers []result
+	seen   map[string]int
+}
+
+func (bw *bibtexWriter) write(r result) error {
+	if bw.seen == nil {
+		bw.seen = make(map[string]int)
+	}
+	// untitled results without a doi can't be matched up
+	var keys []string
+	if title := strings.ToLower(strings.Join(strings.Fields(r.Title), " ")); title != "" {
+		keys = append(keys, "title:"+title)
+	}
+	if r.DOI != "" {
+		keys = append(keys, "doi:"+strings.ToLower(r.DOI))
+	}
+	for _, k := range keys {
+		if i, ok := bw.seen[k]; ok {
+			bw.papers[i] = fillPaper(bw.papers[i], r)
+			for _, k := range keys {
+				bw.seen[k] = i
+			}
+			return nil
+		}
+	}
+	for _, k := range keys {
+		bw.seen[k] = len(bw.papers)
+	}
+	bw.papers = append(bw.papers, r)
+	return nil
+}
+
+// fillPaper fills in what p is missing from r.
+func fillPaper(p, r result) result {
+	if len(p.Authors) == 0 {
+		p.Authors = r.Authors
+	}
+	if p.Year == 0 {
+		p.Year = r.Year
+	}
+	if p.DOI == "" {
+		p.DOI = r.DOI
+	}
+	if p.Venue == "" || p.Venue == "arXiv" {
+		if r.Venue != "" {
+			p.Venue = r.Venue
+		}
+	}
+	if p.PDF == "" {
+		p.PDF = r.PDF
+	}
+	if p.Blurb == "" {
+		p.Blurb = r.Blurb
+	}
+	return p
+}
+
+func (bw *bibtexWriter) close() error {
+	used := make(map[string]bool)
+	for _, p := range bw.papers {
+		base := bibKey(p)
+		key := base
+		// smith2020go, smith2020gob, ..., skipping keys already used
+		for n := 1; used[key]; n++ {
+			if n < 26 {
+				key = base + string(rune('a'+n))
+			} else {
+				key = base + strconv.Itoa(n)
+			}
+		}
+		used[key] = true
+		_, err := io.WriteString(bw.s.output, bibEntry(key, p))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bibKey is the first author's last name, the year, and the first
+// word of the title, like pike2009go.
+func bibKey(p result) string {
+	var author string
+	if len(p.Authors) > 0 {
+		author = lastName(p.Authors[0])
+	}
+	var word string
+	for _, w := range strings.Fields(p.Title) {
+		if w = strings.ToLower(w); w != "a" && w != "an" && w != "the" {
+			word = w
+			break
+		}
+	}
+	key := strings.ToLower(author)
+	if p.Year > 0 {
+		key += strconv.Itoa(p.Year)
+	}
+	key += word
+	keep := func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}
+	if key = strings.Map(keep, key); key == "" {
+		key = "result"
+	}
+	return key
+}
+
+// bibEscaper escapes the characters BibTeX treats specially.
+var bibEscaper = strings.NewReplacer(`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`)
+
+func bibEntry(key string, p result) string {
+	kind := "misc"
+	venueField := "howpublished"
+	if p.Venue != "" && p.Venue != "arXiv" {
+		kind = "article"
+		venueField = "journal"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "@%s{%s,\n", kind, key)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "  %s = {%s},\n", name, value)
+		}
+	}
+	field("title", bibEscaper.Replace(p.Title))
+	field("author", bibEscaper.Replace(strings.Join(p.Authors, " and ")))
+	if p.Year > 0 {
+		field("year", strconv.Itoa(p.Year))
+	}
+	field(venueField, bibEscaper.Replace(p.Venue))
+	// urls and dois are left as they are, for the url package
+	field("doi", p.DOI)
+	field("url", p.URL)
+	field("pdf", p.PDF)
+	b.WriteString("}\n\n")
+	return b.String()
+}
@@ -0,0 +1,233 @@
+package search_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+var outputResults = []search.Result{
+	{Query: "golang", Engine: "brave", Rank: 1, Title: "The Go Programming Language", URL: "https://go.dev/", Blurb: `Go is "simple", secure,` + "\nand scalable"},
+	{Query: "golang", Engine: "brave", Rank: 2, Title: "spf13/cobra", URL: "https://github.com/spf13/cobra", Blurb: "A Commander for modern Go CLI interactions"},
+	{Query: "golang", Engine: "mojeek", Rank: 1, Title: "Go", URL: "https://go.dev/", Blurb: "The Go programming language"},
+}
+
+func writeOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs(append([]string{"-s", "golang", "-n", "-color", "never"}, args...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write(outputResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func readCSV(t *testing.T, data string, comma rune) [][]string {
+	t.Helper()
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = comma
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+	rows := readCSV(t, writeOutput(t, "-o", "csv"), ',')
+	if len(rows) != 4 {
+		t.Fatalf("want a header and 3 rows, got %q", rows)
+	}
+	if got := strings.Join(rows[0], ","); got != "query,engine,rank,title,url,blurb" {
+		t.Errorf("header: got %q", got)
+	}
+	want := []string{"golang", "brave", "1", "The Go Programming Language", "https://go.dev/", outputResults[0].Blurb}
+	if strings.Join(rows[1], "|") != strings.Join(want, "|") {
+		t.Errorf("want %q, got %q", want, rows[1])
+	}
+}
+
+func TestTSVColumns(t *testing.T) {
+	t.Parallel()
+	out := writeOutput(t, "-o", "tsv", "-columns", "url,rank")
+	want := "url\trank\nhttps://go.dev/\t1\nhttps://github.com/spf13/cobra\t2\nhttps://go.dev/\t1\n"
+	if out != want {
+		t.Fatalf("want %q, got %q", want, out)
+	}
+}
+
+func TestCSVMerged(t *testing.T) {
+	t.Parallel()
+	rows := readCSV(t, writeOutput(t, "-o", "csv", "-merge", "-columns", "rank,engine,url"), ',')
+	want := [][]string{
+		{"rank", "engine", "url"},
+		{"1", "brave, mojeek", "https://go.dev/"},
+		{"2", "brave", "https://github.com/spf13/cobra"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("want %q, got %q", want, rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d: want %q, got %q", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestCSVEmpty(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs([]string{"-s", "golang", "-n", "-o", "csv"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "query,engine,rank,title,url,blurb\n" {
+		t.Fatalf("want only the header, got %q", out.String())
+	}
+}
+
+var paperResults = []search.Result{
+	{Query: "go concurrency", Engine: "arxiv", Rank: 1, Title: "Understanding Real-World Concurrency Bugs in Go", URL: "http://arxiv.org/abs/2204.00764v1", Authors: []string{"Tengfei Tu", "Xiaoyu Liu", "Linhai Song", "Yiying Zhang"}, Year: 2022, DOI: "10.1145/3297858.3304069", Venue: "arXiv", PDF: "http://arxiv.org/pdf/2204.00764v1"},
+	{Query: "go concurrency", Engine: "crossref", Rank: 1, Title: "Understanding real-world concurrency bugs in Go", URL: "https://doi.org/10.1145/3297858.3304069", Authors: []string{"Tengfei Tu"}, Year: 2019, DOI: "10.1145/3297858.3304069", Venue: "ASPLOS"},
+	{Query: "go concurrency", Engine: "semanticscholar", Rank: 2, Title: "Go & Rust: Memory_Safety", URL: "https://www.semanticscholar.org/paper/1a2b", Authors: []string{"Kim Lee", "Sam Park"}, Year: 2023},
+}
+
+func writePapers(t *testing.T, args ...string) string {
+	t.Helper()
+	var out strings.Builder
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs(append([]string{"-s", "go concurrency", "-n", "-color", "never"}, args...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write(paperResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestJSONOutput(t *testing.T) {
+	t.Parallel()
+	var got []search.Result
+	err := json.Unmarshal([]byte(writePapers(t, "-o", "json")), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, paperResults) {
+		t.Errorf("want %+v, got %+v", paperResults, got)
+	}
+}
+
+func TestBibTeX(t *testing.T) {
+	t.Parallel()
+	got := writePapers(t, "-o", "bibtex")
+	// arxiv and crossref found the same paper
+	want := `@article{tu2022understanding,
+  title = {Understanding Real-World Concurrency Bugs in Go},
+  author = {Tengfei Tu and Xiaoyu Liu and Linhai Song and Yiying Zhang},
+  year = {2022},
+  journal = {ASPLOS},
+  doi = {10.1145/3297858.3304069},
+  url = {http://arxiv.org/abs/2204.00764v1},
+  pdf = {http://arxiv.org/pdf/2204.00764v1},
+}
+
+@misc{lee2023go,
+  title = {Go \& Rust: Memory\_Safety},
+  author = {Kim Lee and Sam Park},
+  year = {2023},
+  url = {https://www.semanticscholar.org/paper/1a2b},
+}
+
+`
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestPaperColumns(t *testing.T) {
+	t.Parallel()
+	rows := readCSV(t, writePapers(t, "-o", "csv", "-columns", "engine,authors,year,doi"), ',')
+	want := []string{"arxiv", "Tengfei Tu; Xiaoyu Liu; Linhai Song; Yiying Zhang", "2022", "10.1145/3297858.3304069"}
+	if len(rows) != 4 || strings.Join(rows[1], "|") != strings.Join(want, "|") {
+		t.Errorf("want %q after the header, got %q", want, rows)
+	}
+}
+
+func TestBibTeXUntitled(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs([]string{"-s", "golang", "-n", "-o", "bibtex"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write([]search.Result{
+		{Engine: "arxiv", URL: "https://example.com/a"},
+		{Engine: "crossref", URL: "https://example.com/b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nothing ties them together, so each gets an entry
+	if n := strings.Count(out.String(), "@misc{"); n != 2 {
+		t.Errorf("want 2 entries, got %d in\n%s", n, out.String())
+	}
+}
+
+func TestBibTeXKeys(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs([]string{"-s", "golang", "-n", "-o", "bibtex"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smith := []string{"Ann Smith"}
+	_, err = s.Write([]search.Result{
+		{Title: "Gob Encoding", Authors: smith, Year: 2020},
+		{Title: "Go Modules", Authors: smith, Year: 2020},
+		{Title: "Go Generics", Authors: smith, Year: 2020},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "@misc{") {
+			keys = append(keys, strings.TrimSuffix(strings.TrimPrefix(line, "@misc{"), ","))
+		}
+	}
+	// smith2020gob is taken, so the second go paper skips it
+	want := []string{"smith2020gob", "smith2020go", "smith2020goc"}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Errorf("want keys %v, got %v", want, keys)
+	}
+}
@@ -0,0 +1,294 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/davemolk/fuzzyHelpers"
+	"golang.org/x/net/html"
+)
+
+// maxRedirects is how many redirects follow will chase.
+const maxRedirects = 10
+
+// maxPageSize caps how much of a result page we read.
+const maxPageSize = 5 << 20
+
+// page is the readable text of a result page.
+type page struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// follow makes a request to u with browser headers, following up
+// to maxRedirects redirects itself since s.client doesn't, and
+// returns the final response along with every URL visited.
+func (s *searcher) follow(ctx context.Context, method, u string) (*http.Response, []string, error) {
+	var chain []string
+	for i := 0; i <= maxRedirects; i++ {
+		chain = append(chain, u)
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return nil, chain, fmt.Errorf("unable to create request for %s: %v", u, err)
+		}
+		h := fuzzyHelpers.NewHeaders(
+			fuzzyHelpers.WithURL(u),
+			fuzzyHelpers.WithOS(s.osys),
+		)
+		req.Header = h.Headers()
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, chain, fmt.Errorf("unable to make request for %s: %v", u, err)
+		}
+		loc := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode > 399 || loc == "" {
+			return resp, chain, nil
+		}
+		resp.Body.Close()
+		next, err := req.URL.Parse(loc)
+		if err != nil {
+			return nil, chain, fmt.Errorf("bad redirect from %s: %v", u, err)
+		}
+		u = next.String()
+	}
+	return nil, chain, fmt.Errorf("too many redirects for %s", chain[0])
+}
+
+// readPage downloads u and extracts its title and text.
+func (s *searcher) readPage(ctx context.Context, u string) (page, error) {
+	if p, err := url.Parse(u); err != nil || (p.Scheme != "http" && p.Scheme != "https") {
+		return page{}, fmt.Errorf("not a web url: %s", u)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.timeout)*time.Millisecond)
+	defer cancel()
+
+	resp, chain, err := s.follow(ctx, http.MethodGet, u)
+	if err != nil {
+		return page{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return page{}, fmt.Errorf("HTTP response: %d for %s", resp.StatusCode, u)
+	}
+	p := page{URL: chain[len(chain)-1]}
+	body := io.LimitReader(resp.Body, maxPageSize)
+	ct := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "text/plain") {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return page{}, fmt.Errorf("unable to read %s: %w", u, err)
+		}
+		p.Text = strings.TrimSpace(string(b))
+		return p, nil
+	}
+	if ct != "" && !strings.Contains(ct, "html") {
+		return page{}, fmt.Errorf("unsupported content type %q for %s", ct, u)
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return page{}, fmt.Errorf("cannot parse response body: %w", err)
+	}
+	p.Title = s.cleanBlurb(doc.Find("title").First().Text())
+	p.Text = s.pageText(doc)
+	return p, nil
+}
+
+// boilerplate matches class and id values of page furniture that
+// is rarely part of the main text.
+var boilerplate = regexp.MustCompile(`(?i)\b(ad|ads|advert\w*|banner|breadcrumbs?|comments?|cookie\w*|footer|masthead|menu|nav\w*|newsletter|popup|promo\w*|related|share|sharing|sidebar|social|sponsor\w*|subscribe|widget)\b`)
+
+// pageText returns the main text of doc, one line per block. Like
+// readability, it drops page furniture, then scores the containers
+// of each paragraph by how much prose they hold and keeps the best.
+func (s *searcher) pageText(doc *goquery.Document) string {
+	doc.Find("script, style, noscript, template, svg, iframe, form, nav, header, footer, aside").Remove()
+	doc.Find("[class], [id]").Each(func(_ int, g *goquery.Selection) {
+		class, _ := g.Attr("class")
+		id, _ := g.Attr("id")
+		if g.Is("body, html, article, main") {
+			return
+		}
+		if boilerplate.MatchString(class + " " + id) {
+			g.Remove()
+		}
+	})
+
+	root := doc.Find("article, main, [role=main]").First()
+	if root.Length() == 0 {
+		root = s.bestContainer(doc)
+	}
+	if root.Length() == 0 {
+		root = doc.Find("body")
+	}
+
+	var lines []string
+	root.Find("h1, h2, h3, h4, h5, h6, p, li, pre, blockquote, td").Each(func(_ int, g *goquery.Selection) {
+		// skip blocks nested in one we've already taken
+		if g.ParentsFiltered("p, li, pre, blockquote, td").Length() > 0 {
+			return
+		}
+		if line := s.cleanBlurb(g.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	})
+	return strings.Join(lines, "\n")
+}
+
+// bestContainer scores the parent (fully) and grandparent (by half)
+// of every paragraph by its length and commas, discounts each by
+// the share of its text that is links, and returns the winner.
+func (s *searcher) bestContainer(doc *goquery.Document) *goquery.Selection {
+	type candidate struct {
+		sel   *goquery.Selection
+		score float64
+	}
+	var candidates []*candidate
+	scores := make(map[*html.Node]*candidate)
+	add := func(sel *goquery.Selection, score float64) {
+		if sel.Length() == 0 {
+			return
+		}
+		node := sel.Get(0)
+		c, ok := scores[node]
+		if !ok {
+			c = &candidate{sel: sel}
+			scores[node] = c
+			candidates = append(candidates, c)
+		}
+		c.score += score
+	}
+	doc.Find("p, pre").Each(func(_ int, g *goquery.Selection) {
+		text := s.cleanBlurb(g.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		parent := g.Parent()
+		add(parent, score)
+		add(parent.Parent(), score/2)
+	})
+
+	var best *candidate
+	for _, c := range candidates {
+		text := len(c.sel.Text())
+		if text == 0 {
+			continue
+		}
+		links := 0
+		c.sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+			links += len(a.Text())
+		})
+		c.score *= 1 - float64(links)/float64(text)
+		if best == nil || c.score > best.score {
+			best = c
+		}
+	}
+	if best == nil {
+		return &goquery.Selection{}
+	}
+	return best.sel
+}
+
+// readPages passes results through, attaching the readable text of
+// the s.fetchPages best ranked unique result urls. Which those are is
+// only known once every engine has answered, so results are held
+// until then. A page that can't be read leaves its result without
+// text.
+func (s *searcher) readPages(ctx context.Context, in <-chan result) <-chan result {
+	top := make(map[string]bool)
+	ranked := make(chan result)
+	go func() {
+		defer close(ranked)
+		var all []result
+		for r := range in {
+			all = append(all, r)
+		}
+		byRank := append([]result{}, all...)
+		sort.SliceStable(byRank, func(i, j int) bool {
+			return byRank[i].Rank < byRank[j].Rank
+		})
+		for _, r := range byRank {
+			if len(top) >= s.fetchPages {
+				break
+			}
+			if r.URL != "" {
+				top[r.URL] = true
+			}
+		}
+		for _, r := range all {
+			select {
+			case ranked <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	seen := make(map[string]bool)
+	want := func(r result) bool {
+		if !top[r.URL] || seen[r.URL] {
+			return false
+		}
+		seen[r.URL] = true
+		return true
+	}
+	return s.process(ctx, ranked, want, func(ctx context.Context, r *result) bool {
+		p, err := s.readPage(ctx, r.URL)
+		if err != nil && s.debug {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		r.Text = p.Text
+		return true
+	})
+}
+
+// process runs fn on each result from in that want accepts, up to
+// s.concurrency at a time (sharing s.tokens with the search
+// requests), and sends on the kept results. Results want rejects
+// pass straight through. want is called from a single goroutine.
+func (s *searcher) process(ctx context.Context, in <-chan result, want func(result) bool, fn func(context.Context, *result) bool) <-chan result {
+	out := make(chan result)
+	go func() {
+		defer close(out)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		send := func(r result) {
+			select {
+			case out <- r:
+			case <-ctx.Done():
+			}
+		}
+		for r := range in {
+			if !want(r) {
+				send(r)
+				continue
+			}
+			wg.Add(1)
+			go func(r result) {
+				defer wg.Done()
+				select {
+				case s.tokens <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				keep := fn(ctx, &r)
+				<-s.tokens
+				if keep {
+					send(r)
+				}
+			}(r)
+		}
+	}()
+	return out
+}
@@ -0,0 +1,118 @@
+package search_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+func TestPageTextDropsBoilerplate(t *testing.T) {
+	t.Parallel()
+	doc, err := os.ReadFile("testdata/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "foo", "-n"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.PageText(string(doc))
+	want := "Why gophers dig\n" +
+		"Pocket gophers spend almost their entire lives underground, digging tunnels that can stretch for hundreds of feet.\n" +
+		"Their burrows protect them from predators, keep them cool in summer, and give them access to the roots they eat.\n" +
+		"A single gopher can move a ton of soil in a year, which aerates the ground, mixes nutrients, and helps plants grow.\n" +
+		"Tunnels for food\n" +
+		"Chambers for nesting"
+	if got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestPageTextPrefersArticle(t *testing.T) {
+	t.Parallel()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "foo", "-n"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := `<html><body><div><p>Outside the article, with plenty of words, commas, and more.</p></div>
+<article><h2>Inside</h2><p>Short.</p></article></body></html>`
+	if got, want := s.PageText(doc), "Inside\nShort."; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestReadPages(t *testing.T) {
+	t.Parallel()
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprintf(w, "<html><body><p>page %s</p></body></html>", r.URL.Path)
+	}))
+	defer ts.Close()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "foo", "-n", "-fetch", "2", "-c", "1"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.ReadPages([]search.Result{
+		{Engine: "brave", URL: ts.URL + "/a"},
+		{Engine: "mojeek", URL: ts.URL + "/a"},
+		{Engine: "brave", URL: ts.URL + "/b"},
+		{Engine: "brave", URL: ts.URL + "/c"},
+	})
+	var texts []string
+	for _, r := range got {
+		texts = append(texts, r.Engine+" "+r.Text)
+	}
+	sort.Strings(texts)
+	want := []string{"brave ", "brave page /a", "brave page /b", "mojeek "}
+	if fmt.Sprint(texts) != fmt.Sprint(want) {
+		t.Errorf("got %q want %q", texts, want)
+	}
+	if hits := atomic.LoadInt32(&hits); hits != 2 {
+		t.Errorf("got %d page requests want 2", hits)
+	}
+}
+
+func TestReadPagesByRank(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><body><p>page %s</p></body></html>", r.URL.Path)
+	}))
+	defer ts.Close()
+	s, err := search.NewSearcher(
+		search.FromArgs([]string{"-s", "foo", "-n", "-fetch", "1"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mojeek answers last but has the top result
+	got := s.ReadPages([]search.Result{
+		{Engine: "brave", Rank: 2, URL: ts.URL + "/b"},
+		{Engine: "brave", Rank: 3, URL: ts.URL + "/c"},
+		{Engine: "mojeek", Rank: 1, URL: ts.URL + "/a"},
+	})
+	for _, r := range got {
+		want := ""
+		if r.Rank == 1 {
+			want = "page /a"
+		}
+		if r.Text != want {
+			t.Errorf("rank %d: got text %q want %q", r.Rank, r.Text, want)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("got %d results want 3", len(got))
+	}
+}
@@ -1,13 +1,47 @@
 package search
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
 
 type query struct {
 	base          string
 	blurbSelector string
-	itemSelector  string
-	linkSelector  string
-	name          string
+	// decode parses engines that answer in json rather than html
+	decode func(io.Reader) ([]result, error)
+	// extract fills in fields the selectors don't cover
+	extract func(*goquery.Selection, *result)
+	// header is sent with each request, e.g. an api key
+	header       map[string]string
+	itemSelector string
+	linkSelector string
+	// localize returns the parameters for -region, -lang, and -safe
+	localize  func(locale) string
+	name      string
+	pageParam string
+	pageSize  int
+	pageStart int
+	// since holds the parameter limiting results to the past day,
+	// week, month, or year
+	since         map[string]string
+	titleSelector string
+	// verify sends requests through s.verified, for engines that
+	// are sent an api key or token
+	verify bool
+}
+
+// engineNames lists every supported search engine.
+var engineNames = []string{
+	"arxiv", "bing", "brave", "crossref", "duck", "ecosia", "github",
+	"githubcode", "hn", "mojeek", "pkgsite", "qwant", "searxng",
+	"semanticscholar", "stackoverflow", "startpage", "wikipedia",
+	"wiktionary", "yahoo",
 }
 
 func (s *searcher) CreateQueries() {
@@ -16,146 +50,342 @@ func (s *searcher) CreateQueries() {
 		blurbSelector: "div.b_caption p",
 		itemSelector:  "li.b_algo",
 		linkSelector:  "h2 a",
+		localize:      bingLocale,
 		name:          "bing",
+		pageParam:     "first",
+		pageSize:      10,
+		pageStart:     1,
+		since:         sinceParams("filters", "ex1%3a%22ez1%22", "ex1%3a%22ez2%22", "ex1%3a%22ez3%22", ""),
+		titleSelector: "h2 a",
 	}
 	s.brave = &query{
 		base:          "https://search.brave.com/search?q=",
 		blurbSelector: "div.snippet-content p.snippet-description",
 		itemSelector:  "div.fdb",
 		linkSelector:  "div.fdb > a.result-header",
+		localize:      braveLocale,
 		name:          "brave",
+		pageParam:     "offset",
+		pageSize:      1,
+		pageStart:     0,
+		since:         sinceParams("tf", "pd", "pw", "pm", "py"),
+		titleSelector: "div.fdb > a.result-header span.snippet-title",
 	}
 	s.duck = &query{
 		base:          "https://html.duckduckgo.com/html?q=",
 		blurbSelector: "div.links_main > a",
 		itemSelector:  "div.web-result",
 		linkSelector:  "div.links_main > a",
+		localize:      duckLocale,
 		name:          "duck",
+		pageParam:     "s",
+		pageSize:      30,
+		pageStart:     0,
+		since:         sinceParams("df", "d", "w", "m", "y"),
+		titleSelector: "h2.result__title > a",
+	}
+	s.ecosia = &query{
+		base:          "https://www.ecosia.org/search?method=index&q=",
+		blurbSelector: "[data-test-id='web-result-description']",
+		itemSelector:  "[data-test-id='mainline-result-web']",
+		linkSelector:  "a[data-test-id='result-link']",
+		name:          "ecosia",
+		pageParam:     "p",
+		pageSize:      1,
+		pageStart:     0,
+		titleSelector: "[data-test-id='result-title']",
 	}
 	s.mojeek = &query{
 		base:          "https://www.mojeek.com/search?q=",
 		blurbSelector: "li > p.s",
 		itemSelector:  "ul.results-standard > li",
 		linkSelector:  "li > a.ob",
+		localize:      mojeekLocale,
 		name:          "mojeek",
+		pageParam:     "s",
+		pageSize:      10,
+		pageStart:     1,
+		titleSelector: "li > h2 > a",
 	}
 	s.qwant = &query{
 		base:          "https://lite.qwant.com/?q=",
 		blurbSelector: "article[class='web result'] > p.desc",
 		itemSelector:  "article[class='web result']",
 		linkSelector:  "article[class='web result'] > span",
+		localize:      qwantLocale,
 		name:          "qwant",
+		pageParam:     "p",
+		pageSize:      1,
+		pageStart:     1,
+		titleSelector: "article[class='web result'] > h2 > a",
+	}
+	s.searxng = &query{
+		decode:    decodeSearXNG,
+		localize:  searxngLocale,
+		name:      "searxng",
+		pageParam: "pageno",
+		pageSize:  1,
+		pageStart: 1,
+		since:     sinceParams("time_range", "day", "week", "month", "year"),
+	}
+	if s.searxngURL != "" {
+		s.searxng.base = strings.TrimSuffix(s.searxngURL, "/") + "/search?format=json&q="
+	}
+	s.startpage = &query{
+		base:          "https://www.startpage.com/sp/search?query=",
+		blurbSelector: "p.description",
+		itemSelector:  "div.result",
+		linkSelector:  "a.result-link",
+		localize:      startpageLocale,
+		name:          "startpage",
+		pageParam:     "page",
+		pageSize:      1,
+		pageStart:     1,
+		since:         sinceParams("with_date", "d", "w", "m", "y"),
+		titleSelector: "a.result-link h2",
 	}
 	s.yahoo = &query{
 		base:          "https://search.yahoo.com/search?p=",
 		blurbSelector: "div.compText",
 		itemSelector:  "div.algo",
 		linkSelector:  "h3 > a",
+		localize:      yahooLocale,
 		name:          "yahoo",
+		pageParam:     "b",
+		pageSize:      10,
+		pageStart:     1,
+		since:         sinceParams("btf", "d", "w", "m", ""),
+		titleSelector: "h3 > a",
+	}
+	s.devQueries()
+	s.wikiQueries()
+	s.academicQueries()
+	s.apiQueries()
+	s.verticalQueries()
+	// point engines somewhere else, e.g. a local stand-in, leaving
+	// those that can't search -type skipped
+	for name, base := range s.bases {
+		if e := s.engine(name); e.searchable() {
+			e.base = base
+		}
 	}
 }
 
+// FormatURL sends a search URL for each additional term and
+// search engine combination to the returned channel.
 func (s *searcher) FormatURL() <-chan string {
-	// 4 search engines
-	out := make(chan string, len(s.terms)*4)
+	engines := s.engines()
+	terms := s.queryTerms()
+	out := make(chan string, len(terms)*len(engines))
+	s.queries = make(map[string]string)
+	s.queryURLs = nil
+	for _, term := range terms {
+		q := s.format(term)
+		for _, e := range engines {
+			u := fmt.Sprintf("%s%s%s%s%s", e.base, escapeQuery(q), e.paginate(s.page), e.since[s.since], e.params(s.locale))
+			s.queries[u] = strings.ReplaceAll(q, "+", " ")
+			s.queryURLs = append(s.queryURLs, u)
+			out <- u
+		}
+	}
+	close(out)
+	return out
+}
+
+// queryTerms returns the additional terms to search for, each
+// combined with the base search term by format.
+func (s *searcher) queryTerms() []string {
+	if s.noTerms {
+		return []string{""}
+	}
+	return s.terms
+}
+
+// engines returns the search engines to query, in order.
+func (s *searcher) engines() []*query {
+	var engines []*query
+	for _, e := range s.requested() {
+		// engines without a base can't search -type
+		if e.base != "" {
+			engines = append(engines, e)
+		}
+	}
+	return engines
+}
+
+// requested returns the engines picked with -engines or -p.
+func (s *searcher) requested() []*query {
+	if len(s.engineNames) > 0 {
+		var engines []*query
+		for _, name := range s.engineNames {
+			engines = append(engines, s.engine(name))
+		}
+		return engines
+	}
+	var engines []*query
+	if s.privacy {
+		engines = []*query{s.brave, s.duck, s.mojeek, s.qwant, s.startpage}
+	} else {
+		engines = []*query{s.bing, s.brave, s.duck, s.yahoo}
+	}
+	// your own instance joins the defaults when set with -searxng
+	if s.searxngDefault {
+		engines = append(engines, s.searxng)
+	}
+	return engines
+}
+
+// allEngines returns every search engine, in the order of engineNames.
+func (s *searcher) allEngines() []*query {
+	return []*query{
+		s.arxiv, s.bing, s.brave, s.crossref, s.duck, s.ecosia, s.github,
+		s.githubCode, s.hn, s.mojeek, s.pkgsite, s.qwant, s.searxng,
+		s.semanticScholar, s.stackoverflow, s.startpage, s.wikipedia,
+		s.wiktionary, s.yahoo,
+	}
+}
+
+// engine returns the search engine called name, or nil.
+func (s *searcher) engine(name string) *query {
+	for _, e := range s.allEngines() {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// engineFor returns the search engine that url was built for.
+func (s *searcher) engineFor(url string) (*query, error) {
+	for _, e := range s.allEngines() {
+		// an engine without a base, like an unset searxng, matches nothing
+		if e.base != "" && strings.HasPrefix(url, e.base) {
+			return e, nil
+		}
+	}
+	// return error if we didn't hit one of these!
+	return nil, fmt.Errorf("mismatched url, check if one of the search engines has changed")
+}
+
+// format combines the base search and term into a query string,
+// adding quotes for exact matching.
+// exact > searchExact > multiExact
+func (s *searcher) format(term string) string {
 	switch {
-	case s.noTerms && s.privacy:
-		go func() {
-			defer close(out)
-			out <- fmt.Sprintf("%s%s", s.brave.base, s.search)
-			out <- fmt.Sprintf("%s%s", s.duck.base, s.search)
-			out <- fmt.Sprintf("%s%s", s.mojeek.base, s.search)
-			out <- fmt.Sprintf("%s%s", s.qwant.base, s.search)
-		}()
 	case s.noTerms:
-		go func() {
-			defer close(out)
-			out <- fmt.Sprintf("%s%s", s.bing.base, s.search)
-			out <- fmt.Sprintf("%s%s", s.brave.base, s.search)
-			out <- fmt.Sprintf("%s%s", s.duck.base, s.search)
-			out <- fmt.Sprintf("%s%s", s.yahoo.base, s.search)
-		}()
-	case s.exact && s.privacy:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.mojeek.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.qwant.base, s.search, term)
-			}
-		}()
+		return s.search
 	case s.exact:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.bing.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s+%s\"", s.yahoo.base, s.search, term)
-			}
-		}()
-	case s.searchExact && s.privacy:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.mojeek.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.qwant.base, s.search, term)
-			}
-		}()
+		return fmt.Sprintf("\"%s+%s\"", s.search, term)
 	case s.searchExact:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.bing.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s\"%s\"+%s", s.yahoo.base, s.search, term)
-			}
-		}()
-	case s.multiExact && s.privacy:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.mojeek.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.qwant.base, s.search, term)
-			}
-		}()
+		return fmt.Sprintf("\"%s\"+%s", s.search, term)
 	case s.multiExact:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.bing.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+\"%s\"", s.yahoo.base, s.search, term)
-			}
-		}()
-	case s.privacy:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s%s+%s", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+%s", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+%s", s.mojeek.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+%s", s.qwant.base, s.search, term)
-			}
-		}()
+		return fmt.Sprintf("%s+\"%s\"", s.search, term)
 	default:
-		go func() {
-			defer close(out)
-			for _, term := range s.terms {
-				out <- fmt.Sprintf("%s%s+%s", s.bing.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+%s", s.brave.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+%s", s.duck.base, s.search, term)
-				out <- fmt.Sprintf("%s%s+%s", s.yahoo.base, s.search, term)
-			}
-		}()
+		return fmt.Sprintf("%s+%s", s.search, term)
 	}
+}
 
-	return out
+// escapeQuery escapes each word of q, a query joined by +, for use
+// in a url, keeping the quotes around words for exact matching.
+func escapeQuery(q string) string {
+	words := strings.Split(q, "+")
+	for i, w := range words {
+		inner := strings.Trim(w, `"`)
+		if inner == "" {
+			continue
+		}
+		start := strings.Index(w, inner)
+		words[i] = w[:start] + url.QueryEscape(inner) + w[start+len(inner):]
+	}
+	return strings.Join(words, "+")
+}
+
+// paginate returns the query parameter requesting the given
+// zero-indexed page of results, or an empty string for the
+// first page.
+func (q *query) paginate(page int) string {
+	if page <= 0 || q.pageParam == "" {
+		return ""
+	}
+	return fmt.Sprintf("&%s=%d", q.pageParam, q.pageStart+q.pageSize*page)
+}
+
+// params returns the parameters for -region, -lang, and -safe, if
+// the engine takes them.
+func (q *query) params(l locale) string {
+	if q.localize == nil {
+		return ""
+	}
+	return q.localize(l)
+}
+
+// sinceParams maps day, week, month, and year to param with each
+// of values, leaving out those that are empty.
+func sinceParams(param string, values ...string) map[string]string {
+	since := make(map[string]string)
+	for i, v := range values {
+		if v != "" {
+			since[sinceRanges[i]] = "&" + param + "=" + v
+		}
+	}
+	return since
+}
+
+// sinceRanges are the time ranges -since can limit results to.
+var sinceRanges = []string{"day", "week", "month", "year"}
+
+// unlimited returns the engines being searched that can't limit
+// their results to -since.
+func (s *searcher) unlimited() []string {
+	if s.since == "" {
+		return nil
+	}
+	var names []string
+	for _, e := range s.engines() {
+		if e.since[s.since] == "" {
+			names = append(names, e.name)
+		}
+	}
+	return names
+}
+
+// decodeSearXNG reads the results of a SearXNG instance's json api.
+func decodeSearXNG(body io.Reader) ([]result, error) {
+	var resp struct {
+		Results []struct {
+			URL           string `json:"url"`
+			Title         string `json:"title"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"publishedDate"`
+			Author        string `json:"author"`
+			ImgSrc        string `json:"img_src"`
+			ThumbnailSrc  string `json:"thumbnail_src"`
+			Thumbnail     string `json:"thumbnail"`
+			Resolution    string `json:"resolution"`
+			Length        string `json:"length"`
+		} `json:"results"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Results {
+		res := result{
+			Title:     r.Title,
+			URL:       r.URL,
+			Blurb:     r.Content,
+			Date:      r.PublishedDate,
+			Source:    r.Author,
+			Image:     r.ImgSrc,
+			Thumbnail: r.ThumbnailSrc,
+			Duration:  r.Length,
+		}
+		if res.Thumbnail == "" {
+			res.Thumbnail = r.Thumbnail
+		}
+		res.Width, res.Height = dimensions(r.Resolution)
+		results = append(results, res)
+	}
+	return results, nil
 }
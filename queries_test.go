@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davemolk/search"
@@ -27,6 +28,7 @@ func TestFormatURLNoTerms(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo",
 		"https://www.mojeek.com/search?q=foo",
 		"https://lite.qwant.com/?q=foo",
+		"https://www.startpage.com/sp/search?query=foo",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -65,6 +67,7 @@ func TestFormatURLNoTermsMultiTermBase(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar+baz",
 		"https://www.mojeek.com/search?q=foo+bar+baz",
 		"https://lite.qwant.com/?q=foo+bar+baz",
+		"https://www.startpage.com/sp/search?query=foo+bar+baz",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -89,6 +92,7 @@ func TestFormatURLSingleTerm(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar",
 		"https://www.mojeek.com/search?q=foo+bar",
 		"https://lite.qwant.com/?q=foo+bar",
+		"https://www.startpage.com/sp/search?query=foo+bar",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -129,6 +133,7 @@ func TestFormatURLSingleTermArg(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar",
 		"https://www.mojeek.com/search?q=foo+bar",
 		"https://lite.qwant.com/?q=foo+bar",
+		"https://www.startpage.com/sp/search?query=foo+bar",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -169,10 +174,12 @@ func TestFormatURLMultipleTerms(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar",
 		"https://www.mojeek.com/search?q=foo+bar",
 		"https://lite.qwant.com/?q=foo+bar",
+		"https://www.startpage.com/sp/search?query=foo+bar",
 		"https://search.brave.com/search?q=foo+baz",
 		"https://html.duckduckgo.com/html?q=foo+baz",
 		"https://www.mojeek.com/search?q=foo+baz",
 		"https://lite.qwant.com/?q=foo+baz",
+		"https://www.startpage.com/sp/search?query=foo+baz",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -217,10 +224,12 @@ func TestFormatURLMultipleTermArgs(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar",
 		"https://www.mojeek.com/search?q=foo+bar",
 		"https://lite.qwant.com/?q=foo+bar",
+		"https://www.startpage.com/sp/search?query=foo+bar",
 		"https://search.brave.com/search?q=foo+baz",
 		"https://html.duckduckgo.com/html?q=foo+baz",
 		"https://www.mojeek.com/search?q=foo+baz",
 		"https://lite.qwant.com/?q=foo+baz",
+		"https://www.startpage.com/sp/search?query=foo+baz",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -268,6 +277,7 @@ func TestFormatURLCombineTerms(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar+baz",
 		"https://www.mojeek.com/search?q=foo+bar+baz",
 		"https://lite.qwant.com/?q=foo+bar+baz",
+		"https://www.startpage.com/sp/search?query=foo+bar+baz",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -311,6 +321,7 @@ func TestFormatURLCombineTermsArgs(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar+baz",
 		"https://www.mojeek.com/search?q=foo+bar+baz",
 		"https://lite.qwant.com/?q=foo+bar+baz",
+		"https://www.startpage.com/sp/search?query=foo+bar+baz",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -354,10 +365,12 @@ func TestFormatURLHandleMultipleAndSingleTerms(t *testing.T) {
 		"https://html.duckduckgo.com/html?q=foo+bar",
 		"https://www.mojeek.com/search?q=foo+bar",
 		"https://lite.qwant.com/?q=foo+bar",
+		"https://www.startpage.com/sp/search?query=foo+bar",
 		"https://search.brave.com/search?q=foo+go+golang",
 		"https://html.duckduckgo.com/html?q=foo+go+golang",
 		"https://www.mojeek.com/search?q=foo+go+golang",
 		"https://lite.qwant.com/?q=foo+go+golang",
+		"https://www.startpage.com/sp/search?query=foo+go+golang",
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -410,6 +423,7 @@ func TestFormatURLSingleTermExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar"`,
 		`https://www.mojeek.com/search?q="foo+bar"`,
 		`https://lite.qwant.com/?q="foo+bar"`,
+		`https://www.startpage.com/sp/search?query="foo+bar"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -450,6 +464,7 @@ func TestFormatURLSingleTermArgsExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar"`,
 		`https://www.mojeek.com/search?q="foo+bar"`,
 		`https://lite.qwant.com/?q="foo+bar"`,
+		`https://www.startpage.com/sp/search?query="foo+bar"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -490,6 +505,7 @@ func TestFormatURLMultipleTermExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar+baz"`,
 		`https://www.mojeek.com/search?q="foo+bar+baz"`,
 		`https://lite.qwant.com/?q="foo+bar+baz"`,
+		`https://www.startpage.com/sp/search?query="foo+bar+baz"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -530,6 +546,7 @@ func TestFormatURLMultipleTermArgsExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar+baz"`,
 		`https://www.mojeek.com/search?q="foo+bar+baz"`,
 		`https://lite.qwant.com/?q="foo+bar+baz"`,
+		`https://www.startpage.com/sp/search?query="foo+bar+baz"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -571,6 +588,7 @@ func TestFormatURLSingleTermSearchExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo"+bar`,
 		`https://www.mojeek.com/search?q="foo"+bar`,
 		`https://lite.qwant.com/?q="foo"+bar`,
+		`https://www.startpage.com/sp/search?query="foo"+bar`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -611,6 +629,7 @@ func TestFormatURLSingleTermArgsSearchExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo"+bar`,
 		`https://www.mojeek.com/search?q="foo"+bar`,
 		`https://lite.qwant.com/?q="foo"+bar`,
+		`https://www.startpage.com/sp/search?query="foo"+bar`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -651,6 +670,7 @@ func TestFormatURLMultipleBaseSearchTermSearchExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar"+baz`,
 		`https://www.mojeek.com/search?q="foo+bar"+baz`,
 		`https://lite.qwant.com/?q="foo+bar"+baz`,
+		`https://www.startpage.com/sp/search?query="foo+bar"+baz`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -692,6 +712,7 @@ func TestFormatURLMultipleTermMultiExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q=foo+"bar+baz"`,
 		`https://www.mojeek.com/search?q=foo+"bar+baz"`,
 		`https://lite.qwant.com/?q=foo+"bar+baz"`,
+		`https://www.startpage.com/sp/search?query=foo+"bar+baz"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -732,6 +753,7 @@ func TestFormatURLMultipleTermArgsMultiExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q=foo+"bar+baz"`,
 		`https://www.mojeek.com/search?q=foo+"bar+baz"`,
 		`https://lite.qwant.com/?q=foo+"bar+baz"`,
+		`https://www.startpage.com/sp/search?query=foo+"bar+baz"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -773,6 +795,7 @@ func TestFormatURLExactHasPriorityOverSearchExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar+baz"`,
 		`https://www.mojeek.com/search?q="foo+bar+baz"`,
 		`https://lite.qwant.com/?q="foo+bar+baz"`,
+		`https://www.startpage.com/sp/search?query="foo+bar+baz"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -794,6 +817,7 @@ func TestFormatURLExactHasPriorityOverMultiExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar+baz"`,
 		`https://www.mojeek.com/search?q="foo+bar+baz"`,
 		`https://lite.qwant.com/?q="foo+bar+baz"`,
+		`https://www.startpage.com/sp/search?query="foo+bar+baz"`,
 	}
 	compare(t, s.FormatURL(), want)
 }
@@ -815,6 +839,213 @@ func TestFormatURLSearchExactHasPriorityOverMultiExact(t *testing.T) {
 		`https://html.duckduckgo.com/html?q="foo+bar"+baz`,
 		`https://www.mojeek.com/search?q="foo+bar"+baz`,
 		`https://lite.qwant.com/?q="foo+bar"+baz`,
+		`https://www.startpage.com/sp/search?query="foo+bar"+baz`,
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+/* pagination */
+func TestFormatURLPage(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-page", "2", "bar"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://search.brave.com/search?q=foo+bar&offset=1",
+		"https://html.duckduckgo.com/html?q=foo+bar&s=30",
+		"https://www.mojeek.com/search?q=foo+bar&s=11",
+		"https://lite.qwant.com/?q=foo+bar&p=2",
+		"https://www.startpage.com/sp/search?query=foo+bar&page=2",
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+func TestFormatURLPageNoPrivacy(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-p=false", "-page", "3"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://bing.com/search?q=foo&first=21",
+		"https://search.brave.com/search?q=foo&offset=2",
+		"https://html.duckduckgo.com/html?q=foo&s=60",
+		"https://search.yahoo.com/search?p=foo&b=21",
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+func TestFormatURLPageOptIn(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-engines", "ecosia,startpage", "-page", "3"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://www.ecosia.org/search?method=index&q=foo&p=2",
+		"https://www.startpage.com/sp/search?query=foo&page=3",
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+/* time range */
+func TestFormatURLSince(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-engines", "bing,brave,duck,mojeek,startpage,yahoo", "-since", "week"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://bing.com/search?q=foo&filters=ex1%3a%22ez2%22",
+		"https://search.brave.com/search?q=foo&tf=pw",
+		"https://html.duckduckgo.com/html?q=foo&df=w",
+		"https://www.mojeek.com/search?q=foo",
+		"https://www.startpage.com/sp/search?query=foo&with_date=w",
+		"https://search.yahoo.com/search?p=foo&btf=w",
+	}
+	compare(t, s.FormatURL(), want)
+	if got := strings.Join(s.Unlimited(), ","); got != "mojeek" {
+		t.Errorf("want mojeek searching all time, got %q", got)
+	}
+}
+
+func TestFormatURLSinceYear(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-p=false", "-since", "year", "-page", "2"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://bing.com/search?q=foo&first=11",
+		"https://search.brave.com/search?q=foo&offset=1&tf=py",
+		"https://html.duckduckgo.com/html?q=foo&s=30&df=y",
+		"https://search.yahoo.com/search?p=foo&b=11",
+	}
+	compare(t, s.FormatURL(), want)
+	if got := strings.Join(s.Unlimited(), ","); got != "bing,yahoo" {
+		t.Errorf("want bing and yahoo searching all time, got %q", got)
+	}
+}
+
+/* region, language, and safe search */
+func TestFormatURLLocale(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-engines", "bing,brave,duck,ecosia,mojeek,qwant,startpage,yahoo", "-region", "DE", "-lang", "en", "-safe", "strict"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://bing.com/search?q=foo&mkt=en-DE&setlang=en&adlt=strict",
+		"https://search.brave.com/search?q=foo&country=de&lang=en&safesearch=strict",
+		"https://html.duckduckgo.com/html?q=foo&kl=de-en&kp=1",
+		"https://www.ecosia.org/search?method=index&q=foo",
+		"https://www.mojeek.com/search?q=foo&arc=de&lb=en&safe=1",
+		"https://lite.qwant.com/?q=foo&locale=en_DE&s=2",
+		"https://www.startpage.com/sp/search?query=foo&qadf=heavy",
+		"https://search.yahoo.com/search?p=foo&vl=lang_en&vm=r",
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+func TestFormatURLRegionOnly(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-engines", "bing,duck,qwant", "-region", "gb"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	// the language is guessed from the region
+	want := []string{
+		"https://bing.com/search?q=foo&mkt=en-GB&setlang=en",
+		"https://html.duckduckgo.com/html?q=foo&kl=uk-en",
+		"https://lite.qwant.com/?q=foo&locale=en_GB",
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+func TestFormatURLLanguageOnly(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-n", "-engines", "bing,brave", "-lang", "de"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	// without a region there's no market to send bing
+	want := []string{
+		"https://bing.com/search?q=foo&setlang=de",
+		"https://search.brave.com/search?q=foo&lang=de",
+	}
+	compare(t, s.FormatURL(), want)
+}
+
+func TestLeadingDate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		blurb string
+		date  string
+		rest  string
+	}{
+		{"Feb 1, 2023 — Go 1.20 is released.", "Feb 1, 2023", "Go 1.20 is released."},
+		{"1 February 2023 · Go 1.20 is released.", "1 February 2023", "Go 1.20 is released."},
+		{"3 days ago - Go 1.20 is released.", "3 days ago", "Go 1.20 is released."},
+		{"2023-02-01 · Go 1.20 is released.", "2023-02-01", "Go 1.20 is released."},
+		{"Go 1.20 was released Feb 1, 2023 - on time.", "", "Go 1.20 was released Feb 1, 2023 - on time."},
+	}
+	for _, tt := range tests {
+		date, rest := search.LeadingDate(tt.blurb)
+		if date != tt.date || rest != tt.rest {
+			t.Errorf("%q: want %q, %q, got %q, %q", tt.blurb, tt.date, tt.rest, date, rest)
+		}
+	}
+}
+
+/* engine selection */
+func TestFormatURLEngines(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-engines", "yahoo,mojeek", "bar", "baz"}
+	s, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	want := []string{
+		"https://search.yahoo.com/search?p=foo+bar",
+		"https://www.mojeek.com/search?q=foo+bar",
+		"https://search.yahoo.com/search?p=foo+baz",
+		"https://www.mojeek.com/search?q=foo+baz",
 	}
 	compare(t, s.FormatURL(), want)
 }
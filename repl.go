@@ -0,0 +1,280 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const replHelp = `each line is a query: the first word (or "quoted phrase") is the
+base search term, followed by any modifiers and additional terms
+	golang cli gophers
+	"go lang" -se cli
+	golang -me cloud native
+	-p=false golang
+
+commands
+:open N      open result N in your browser
+:more        fetch the next page of results for the last query
+:engines     list the search engines used for the last query
+:save FILE   write the current results to FILE
+:help        show this message
+:quit        exit (or ctrl-d)`
+
+// replFlags are the per-query modifiers accepted on a repl line.
+// Everything else is set once for the session.
+var replFlags = map[string]bool{
+	"-e":  true,
+	"-m":  true,
+	"-me": true,
+	"-n":  true,
+	"-p":  true,
+	"-se": true,
+}
+
+// repl is an interactive session that shares its HTTP clients and
+// result cache across queries.
+type repl struct {
+	args    []string
+	cache   *resultCache
+	current *searcher
+	in      io.Reader
+	last    []string
+	opts    []option
+	out     io.Writer
+	results []result
+}
+
+// runREPL reads queries from in until EOF or :quit, using args
+// as session-wide flags (e.g. -c, -t, -os, -l), and opts for every
+// searcher.
+func runREPL(args []string, in io.Reader, out io.Writer, opts ...option) error {
+	// validate session flags up front, and keep the resulting
+	// clients for every query in the session
+	base, err := NewSearcher(append(append([]option{}, opts...),
+		WithOutput(out),
+		FromArgs(append(append([]string{}, args...), "-s", "repl", "-n")),
+	)...)
+	if err != nil {
+		return err
+	}
+	base.CreateQueries()
+	r := &repl{
+		args:  args,
+		cache: newResultCache(),
+		in:    in,
+		opts:  opts,
+		out:   out,
+	}
+	r.current = base
+	return r.run()
+}
+
+func (r *repl) run() error {
+	scan := bufio.NewScanner(r.in)
+	fmt.Fprint(r.out, "> ")
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		var err error
+		switch {
+		case line == "":
+		case line == ":q" || line == ":quit":
+			return nil
+		case strings.HasPrefix(line, ":"):
+			err = r.command(line)
+		default:
+			err = r.query(line)
+		}
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+		}
+		fmt.Fprint(r.out, "> ")
+	}
+	fmt.Fprintln(r.out)
+	return scan.Err()
+}
+
+// command runs a session command such as :open 3.
+func (r *repl) command(line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":help", ":h":
+		fmt.Fprintln(r.out, replHelp)
+	case ":open", ":o":
+		if len(fields) != 2 {
+			return errors.New("usage: :open N")
+		}
+		res, err := r.result(fields[1])
+		if err != nil {
+			return err
+		}
+		return openURL(res.URL)
+	case ":more":
+		if r.last == nil {
+			return errors.New("no query to page through yet")
+		}
+		r.current.page++
+		return r.search()
+	case ":engines":
+		var names []string
+		for _, e := range r.current.engines() {
+			names = append(names, e.name)
+		}
+		fmt.Fprintln(r.out, strings.Join(names, " "))
+	case ":save":
+		if len(fields) != 2 {
+			return errors.New("usage: :save FILE")
+		}
+		return r.save(fields[1])
+	default:
+		return fmt.Errorf("unknown command %s, try :help", fields[0])
+	}
+	return nil
+}
+
+// result returns the numbered result n.
+func (r *repl) result(n string) (result, error) {
+	i, err := strconv.Atoi(n)
+	if err != nil || i < 1 || i > len(r.results) {
+		return result{}, fmt.Errorf("no result %s", n)
+	}
+	return r.results[i-1], nil
+}
+
+// query parses line into a new search and runs it.
+func (r *repl) query(line string) error {
+	lineArgs, err := replArgs(line)
+	if err != nil {
+		return err
+	}
+	args := append(append([]string{}, r.args...), lineArgs...)
+	s, err := NewSearcher(append(append([]option{}, r.opts...),
+		WithClient(r.current.client),
+		WithVerifiedClient(r.current.verified),
+		withCache(r.cache),
+		WithInput(strings.NewReader("")),
+		WithOutput(r.out),
+		FromArgs(args),
+	)...)
+	if err != nil {
+		return err
+	}
+	r.current = s
+	r.last = lineArgs
+	r.results = nil
+	return r.search()
+}
+
+// search runs the current query and prints numbered results,
+// continuing the numbering from any earlier pages.
+func (r *repl) search() error {
+	s := r.current
+	s.CreateQueries()
+	var found int
+	for res := range s.stream(context.Background(), s.FormatURL()) {
+		found++
+		r.results = append(r.results, res)
+		fmt.Fprintf(r.out, "%d. ", len(r.results))
+		s.print(res)
+	}
+	if found == 0 {
+		return errors.New("no results")
+	}
+	return nil
+}
+
+// save writes the current results to path.
+func (r *repl) save(path string) error {
+	if len(r.results) == 0 {
+		return errors.New("no results to save")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", path, err)
+	}
+	defer f.Close()
+	// write plain text regardless of session color settings
+	s := *r.current
+	s.output = f
+	s.color = false
+	for i, res := range r.results {
+		fmt.Fprintf(f, "%d. ", i+1)
+		s.print(res)
+	}
+	fmt.Fprintf(r.out, "saved %d results to %s\n", len(r.results), path)
+	return nil
+}
+
+// replArgs turns a repl line into FromArgs arguments. The first
+// word or quoted phrase is the base search term; modifiers may
+// appear anywhere on the line, and the remaining words are
+// additional terms.
+func replArgs(line string) ([]string, error) {
+	words, err := splitArgs(line)
+	if err != nil {
+		return nil, err
+	}
+	var flags, terms []string
+	for _, w := range words {
+		name := strings.SplitN(w, "=", 2)[0]
+		switch {
+		case replFlags[name]:
+			flags = append(flags, w)
+		case strings.HasPrefix(w, "-"):
+			return nil, fmt.Errorf("unsupported modifier %s, use -e, -se, -me, -m, -n, or -p", w)
+		default:
+			terms = append(terms, w)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, ErrNoSearchTerm
+	}
+	args := append(flags, "-s", terms[0])
+	if len(terms) == 1 {
+		return append(args, "-n"), nil
+	}
+	return append(args, terms[1:]...), nil
+}
+
+// splitArgs splits line on spaces, keeping single or double
+// quoted phrases together.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
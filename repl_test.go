@@ -0,0 +1,93 @@
+package search_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+func TestSplitArgs(t *testing.T) {
+	t.Parallel()
+	got, err := search.SplitArgs(`"foo bar" -se  'baz qux' quux`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"foo bar", "-se", "baz qux", "quux"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestSplitArgsUnterminatedQuote(t *testing.T) {
+	t.Parallel()
+	_, err := search.SplitArgs(`"foo bar`)
+	if err == nil {
+		t.Fatal("want error on unterminated quote, got nil")
+	}
+}
+
+func TestReplArgs(t *testing.T) {
+	t.Parallel()
+	tests := map[string][]string{
+		"golang":                  {"-s", "golang", "-n"},
+		"golang cli gophers":      {"-s", "golang", "cli", "gophers"},
+		`"go lang" -se cli`:       {"-se", "-s", "go lang", "cli"},
+		"golang -me cloud native": {"-me", "-s", "golang", "cloud", "native"},
+		"-p=false golang":         {"-p=false", "-s", "golang", "-n"},
+	}
+	for line, want := range tests {
+		got, err := search.ReplArgs(line)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v want %v", line, got, want)
+		}
+	}
+}
+
+func TestReplArgsRejectsSessionFlags(t *testing.T) {
+	t.Parallel()
+	_, err := search.ReplArgs("golang -t 100")
+	if err == nil {
+		t.Fatal("want error on session flag, got nil")
+	}
+}
+
+func TestReplArgsNoSearchTerm(t *testing.T) {
+	t.Parallel()
+	_, err := search.ReplArgs("-e")
+	if !errors.Is(err, search.ErrNoSearchTerm) {
+		t.Fatal("did not fail with ErrNoSearchTerm")
+	}
+}
+
+func TestREPLCommands(t *testing.T) {
+	t.Parallel()
+	in := strings.NewReader(":engines\n:open 1\n:more\n:bogus\n:quit\n:engines\n")
+	var out bytes.Buffer
+	err := search.RunREPL([]string{"-p=false"}, in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "> bing brave duck yahoo\n" +
+		"> error: no result 1\n" +
+		"> error: no query to page through yet\n" +
+		"> error: unknown command :bogus, try :help\n" +
+		"> "
+	if got := out.String(); got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestREPLInvalidSessionFlag(t *testing.T) {
+	t.Parallel()
+	err := search.RunREPL([]string{"-os", "bar"}, strings.NewReader(""), &bytes.Buffer{})
+	if !errors.Is(err, search.ErrInvalidOS) {
+		t.Fatal("did not fail with ErrInvalidOS")
+	}
+}
@@ -0,0 +1,221 @@
+package search
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// report is a whole search, grouped for -o markdown and -o html.
+type report struct {
+	Search  string
+	Time    time.Time
+	Engines []string
+	URLs    []string
+	Merged  bool
+	Groups  []reportGroup
+}
+
+// reportGroup holds the results for a single query: by engine, or
+// merged and ranked with -merge.
+type reportGroup struct {
+	Query   string
+	Engines []reportEngine
+	Entries []entry
+}
+
+type reportEngine struct {
+	Name    string
+	Results []result
+}
+
+// reportWriter collects results and renders them as a report once
+// they're all in.
+type reportWriter struct {
+	s       *searcher
+	results []result
+	render  func(io.Writer, any) error
+}
+
+func (rw *reportWriter) write(r result) error {
+	rw.results = append(rw.results, r)
+	return nil
+}
+
+func (rw *reportWriter) close() error {
+	return rw.render(rw.s.output, rw.s.buildReport(rw.results, time.Now()))
+}
+
+// buildReport groups results by query, in the order of the additional
+// terms, and within each query by engine, in the order they were
+// searched, or merged and ranked.
+func (s *searcher) buildReport(results []result, now time.Time) report {
+	rep := report{
+		Search: strings.ReplaceAll(s.search, "+", " "),
+		Time:   now,
+		URLs:   s.queryURLs,
+		Merged: s.merged,
+	}
+	for _, e := range s.engines() {
+		rep.Engines = append(rep.Engines, e.name)
+	}
+	queries, byQuery := s.byQuery(results)
+	for _, q := range queries {
+		g := reportGroup{Query: q}
+		if s.merged {
+			g.Entries = s.rank(merge(byQuery[q]))
+			rep.Groups = append(rep.Groups, g)
+			continue
+		}
+		byEngine := make(map[string][]result)
+		for _, r := range byQuery[q] {
+			byEngine[r.Engine] = append(byEngine[r.Engine], r)
+		}
+		for _, name := range rep.Engines {
+			found := byEngine[name]
+			sort.SliceStable(found, func(i, j int) bool {
+				return found[i].Rank < found[j].Rank
+			})
+			g.Engines = append(g.Engines, reportEngine{Name: name, Results: found})
+		}
+		rep.Groups = append(rep.Groups, g)
+	}
+	return rep
+}
+
+// byQuery groups results by query, returning the queries in the
+// order of the additional terms, including those without results,
+// followed by any others in the order they were seen.
+func (s *searcher) byQuery(results []result) ([]string, map[string][]result) {
+	var queries []string
+	groups := make(map[string][]result)
+	for _, term := range s.queryTerms() {
+		q := strings.ReplaceAll(s.format(term), "+", " ")
+		if _, ok := groups[q]; !ok {
+			queries = append(queries, q)
+			groups[q] = nil
+		}
+	}
+	for _, r := range results {
+		if _, ok := groups[r.Query]; !ok {
+			queries = append(queries, r.Query)
+		}
+		groups[r.Query] = append(groups[r.Query], r)
+	}
+	return queries, groups
+}
+
+// domain returns the host of u without a leading www.
+func domain(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(p.Hostname(), "www.")
+}
+
+// mdEscaper escapes text that markdown would otherwise format.
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "\n", " ",
+)
+
+var markdownReport = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"domain": domain,
+	"join":   strings.Join,
+	"md":     mdEscaper.Replace,
+	// keep urls from ending the link early
+	"mdURL": strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace,
+	"title": func(title, u string) string {
+		if title != "" {
+			return title
+		}
+		return u
+	},
+}).Parse(`# search: {{md .Search}}
+
+- **generated:** {{.Time.Format "2006-01-02 15:04:05 MST"}}
+- **engines:** {{join .Engines ", "}}
+- **query urls:**
+{{- range .URLs}}
+  - <{{mdURL .}}>
+{{- end}}
+{{- range .Groups}}
+
+## {{md .Query}}
+{{if $.Merged}}
+{{- range .Entries}}
+1. [{{md (title .Title .URL)}}]({{mdURL .URL}}) — {{domain .URL}} ({{join .Engines ", "}})
+{{- if .Blurb}}
+   {{md .Blurb}}
+{{- end}}
+{{- else}}
+_no results_
+{{- end}}
+{{- else}}
+{{- range .Engines}}
+### {{.Name}}
+{{range .Results}}
+1. [{{md (title .Title .URL)}}]({{mdURL .URL}}) — {{domain .URL}}
+{{- if .Blurb}}
+   {{md .Blurb}}
+{{- end}}
+{{- else}}
+_no results_
+{{- end}}
+{{end}}
+{{- end}}
+{{- end}}
+`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"domain": domain,
+	"join":   strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>search: {{.Search}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; padding: 0 1em; line-height: 1.4; }
+header { color: #555; font-size: .9em; border-bottom: 1px solid #ddd; padding-bottom: 1em; }
+header ul { padding-left: 1.2em; }
+.url { word-break: break-all; }
+ol { padding-left: 1.5em; }
+li { margin: 1em 0; }
+li a { font-size: 1.1em; }
+.domain { color: #1a7f37; font-size: .9em; }
+.engines { color: #666; font-size: .8em; }
+.blurb { margin: .2em 0 0; }
+.none { color: #666; font-style: italic; }
+</style>
+</head>
+<body>
+<header>
+<h1>search: {{.Search}}</h1>
+<p>generated {{.Time.Format "2006-01-02 15:04:05 MST"}} from {{join .Engines ", "}}</p>
+<ul>
+{{range .URLs}}<li class="url">{{.}}</li>
+{{end}}</ul>
+</header>
+{{range .Groups}}<section>
+<h2>{{.Query}}</h2>
+{{if $.Merged}}{{if .Entries}}<ol>
+{{range .Entries}}<li><a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a> <span class="domain">{{domain .URL}}</span> <span class="engines">{{join .Engines ", "}}</span>
+{{if .Blurb}}<p class="blurb">{{.Blurb}}</p>{{end}}</li>
+{{end}}</ol>
+{{else}}<p class="none">no results</p>
+{{end}}{{else}}{{range .Engines}}<h3>{{.Name}}</h3>
+{{if .Results}}<ol>
+{{range .Results}}<li><a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a> <span class="domain">{{domain .URL}}</span>
+{{if .Blurb}}<p class="blurb">{{.Blurb}}</p>{{end}}</li>
+{{end}}</ol>
+{{else}}<p class="none">no results</p>
+{{end}}{{end}}{{end}}</section>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,109 @@
+package search_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+var reportResults = []search.Result{
+	{Query: "golang cli", Engine: "brave", Rank: 2, Title: "spf13/cobra", URL: "https://github.com/spf13/cobra", Blurb: "A *Commander* for [modern] Go"},
+	{Query: "golang cli", Engine: "brave", Rank: 1, Title: "The Go Programming Language", URL: "https://www.go.dev/", Blurb: "Build <b>simple</b> systems"},
+	{Query: "golang cli", Engine: "mojeek", Rank: 1, Title: "Go", URL: "https://www.go.dev/", Blurb: "The Go programming language"},
+}
+
+func writeReport(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	args = append([]string{"-s", "golang", "-engines", "brave,mojeek"}, args...)
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs(append(args, "cli", "tui")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.WriteReport(reportResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// inOrder fails unless each of want appears in got after the one
+// before it.
+func inOrder(t *testing.T, got string, want ...string) {
+	t.Helper()
+	rest := got
+	for _, w := range want {
+		i := strings.Index(rest, w)
+		if i < 0 {
+			t.Fatalf("want %q after the previous parts in:\n%s", w, got)
+		}
+		rest = rest[i+len(w):]
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	t.Parallel()
+	out := writeReport(t, "-o", "markdown")
+	inOrder(t, out,
+		"# search: golang",
+		"**generated:** ",
+		"**engines:** brave, mojeek",
+		"<https://search.brave.com/search?q=golang+cli>",
+		"<https://www.mojeek.com/search?q=golang+tui>",
+		"## golang cli",
+		"### brave",
+		"1. [The Go Programming Language](https://www.go.dev/) — go.dev",
+		`Build \<b\>simple\</b\> systems`,
+		"1. [spf13/cobra](https://github.com/spf13/cobra) — github.com",
+		`A \*Commander\* for \[modern\] Go`,
+		"### mojeek",
+		"1. [Go](https://www.go.dev/) — go.dev",
+		"## golang tui",
+		"### brave",
+		"_no results_",
+	)
+}
+
+func TestMarkdownReportMerged(t *testing.T) {
+	t.Parallel()
+	out := writeReport(t, "-o", "markdown", "-merge")
+	inOrder(t, out,
+		"## golang cli",
+		"1. [The Go Programming Language](https://www.go.dev/) — go.dev (brave, mojeek)",
+		"1. [spf13/cobra](https://github.com/spf13/cobra) — github.com (brave)",
+		"## golang tui",
+		"_no results_",
+	)
+	if strings.Contains(out, "### brave") {
+		t.Errorf("merged report grouped by engine:\n%s", out)
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	t.Parallel()
+	out := writeReport(t, "-o", "html")
+	inOrder(t, out,
+		"<!DOCTYPE html>",
+		"<style>",
+		"brave, mojeek",
+		"https://search.brave.com/search?q=golang&#43;cli",
+		"<h2>golang cli</h2>",
+		"<h3>brave</h3>",
+		`<a href="https://www.go.dev/">The Go Programming Language</a>`,
+		"Build &lt;b&gt;simple&lt;/b&gt; systems",
+		"<h2>golang tui</h2>",
+		"no results",
+		"</html>",
+	)
+	// a single file, with nothing to load
+	for _, external := range []string{"<script", "<link", "src=", "@import", "url("} {
+		if strings.Contains(out, external) {
+			t.Errorf("report loads %q", external)
+		}
+	}
+}
@@ -3,10 +3,14 @@ package search
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -16,12 +20,54 @@ import (
 // Search takes in a URL, makes a GET request, and parses the response
 // body, printing the results to s.output.
 func (s *searcher) Search(url string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.timeout)*time.Millisecond)
+	results, err := s.fetch(context.Background(), url)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		s.print(r)
+	}
+	return nil
+}
+
+// fetch makes a GET request to url and parses the response body
+// with the matching search engine's selectors.
+func (s *searcher) fetch(ctx context.Context, url string) ([]result, error) {
+	if s.cache != nil {
+		if results, ok := s.cache.get(url); ok {
+			return results, nil
+		}
+	}
+	parse, err := s.engineFor(url)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.parse(ctx, parse, url)
+	if s.report != nil {
+		s.report(parse.name, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Query = s.queries[url]
+	}
+	if s.cache != nil {
+		s.cache.put(url, results)
+	}
+	return results, nil
+}
+
+// parse requests url and extracts results by scraping with the
+// selectors of search engine parse, or with its decoder for engines
+// that answer in json.
+func (s *searcher) parse(ctx context.Context, parse *query, url string) ([]result, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.timeout)*time.Millisecond)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("unable to create request for %s: %v", url, err)
+		return nil, fmt.Errorf("unable to create request for %s: %v", url, err)
 	}
 
 	// mimic browser headers
@@ -32,56 +78,207 @@ func (s *searcher) Search(url string) error {
 		fuzzyHelpers.WithOS(s.osys),
 	)
 	req.Header = h.Headers()
+	if lang := s.locale.acceptLanguage(); lang != "" {
+		req.Header.Set("Accept-Language", lang)
+	}
+	for k, v := range parse.header {
+		req.Header.Set(k, v)
+	}
 
-	resp, err := s.client.Do(req)
+	client := s.client
+	if parse.verify {
+		client = s.verified
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("unable to make request for %s: %v", url, err)
+		return nil, fmt.Errorf("unable to make request for %s: %v", url, err)
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("HTTP response: %d for %s", resp.StatusCode, url)
+		return nil, fmt.Errorf("HTTP response: %d for %s", resp.StatusCode, url)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	var found []result
+	if parse.decode != nil {
+		found, err = parse.decode(resp.Body)
+	} else {
+		found, err = s.scrape(parse, resp.Body)
+	}
 	if err != nil {
-		return fmt.Errorf("cannot parse response body: %w", err)
+		return nil, fmt.Errorf("cannot parse response body: %w", err)
 	}
 
-	var parse *query
-
-	switch {
-	case strings.HasPrefix(url, "https://search.b"):
-		parse = s.brave
-	case strings.HasPrefix(url, "https://html.d"):
-		parse = s.duck
-	case strings.HasPrefix(url, "https://www.mo"):
-		parse = s.mojeek
-	case strings.HasPrefix(url, "https://lite.qwant"):
-		parse = s.qwant
-	case strings.HasPrefix(url, "https://bing"):
-		parse = s.bing
-	case strings.HasPrefix(url, "https://search.y"):
-		parse = s.yahoo
-	// return error if we didn't hit one of these!
-	default:
-		return fmt.Errorf("mismatched url, check if one of the search engines has changed")
+	var results []result
+	for i, r := range found {
+		if !s.keep(r.URL) {
+			atomic.AddInt64(&s.filtered, 1)
+			continue
+		}
+		r.Engine = parse.name
+		r.Rank = i + 1
+		r.Title = s.cleanBlurb(r.Title)
+		r.Blurb = s.cleanBlurb(r.Blurb)
+		if r.Date == "" {
+			r.Date, r.Blurb = leadingDate(r.Blurb)
+		}
+		results = append(results, r)
 	}
+	return results, nil
+}
 
-	doc.Find(parse.itemSelector).Each(func(_ int, g *goquery.Selection) {
+// scrape extracts results from an html results page using the
+// selectors of search engine parse.
+func (s *searcher) scrape(parse *query, body io.Reader) ([]result, error) {
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	doc.Find(parse.itemSelector).Each(func(i int, g *goquery.Selection) {
 		var link string
 		if parse.name != "qwant" {
 			link, _ = g.Find(parse.linkSelector).Attr("href")
 		} else {
 			link = g.Find(parse.linkSelector).Text()
 		}
-		blurb := g.Find(parse.blurbSelector).Text()
-		cleanedLink := s.cleanLinks(link)
-		cleanedBlurb := s.cleanBlurb(blurb)
-		s.print(cleanedBlurb, cleanedLink)
+		r := result{
+			Title: g.Find(parse.titleSelector).First().Text(),
+			URL:   s.cleanLinks(link),
+			Blurb: g.Find(parse.blurbSelector).Text(),
+		}
+		if parse.extract != nil {
+			parse.extract(g, &r)
+		}
+		results = append(results, r)
 	})
-	return nil
+	return results, nil
+}
+
+// checkLine summarizes a link check, e.g.
+// 200 text/html (via http://a -> http://b)
+func (s *searcher) checkLine(c *linkCheck) string {
+	var status string
+	switch {
+	case c.Error != "":
+		status = s.paint(ansiRed, "error: "+c.Error)
+	case c.dead():
+		status = s.paint(ansiRed, fmt.Sprint(c.Status))
+	default:
+		status = s.paint(ansiGreen, fmt.Sprint(c.Status))
+	}
+	if c.ContentType != "" {
+		status += " " + c.ContentType
+	}
+	if len(c.Redirects) > 0 {
+		status += fmt.Sprintf(" (via %s -> %s)", strings.Join(c.Redirects, " -> "), c.FinalURL)
+	}
+	return status
+}
+
+// result holds the pieces of a single search result.
+type result struct {
+	Query  string `json:"query,omitempty"`
+	Engine string `json:"engine"`
+	Rank   int    `json:"rank"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Blurb  string `json:"blurb"`
+	// news, images, and videos
+	Date      string `json:"date,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Duration  string `json:"duration,omitempty"`
+	// papers
+	Authors []string `json:"authors,omitempty"`
+	Year    int      `json:"year,omitempty"`
+	DOI     string   `json:"doi,omitempty"`
+	Venue   string   `json:"venue,omitempty"`
+	PDF     string   `json:"pdf,omitempty"`
+	// -fetch and -check
+	Text  string     `json:"text,omitempty"`
+	Check *linkCheck `json:"check,omitempty"`
+}
+
+// entry is a result that may have been returned by several
+// search engines.
+type entry struct {
+	result
+	Engines   []string `json:"engines"`
+	Positions []int    `json:"positions"`
+	Score     float64  `json:"score"`
+}
+
+// merge combines results that share a URL, keeping the order in
+// which each URL was first seen, and scores each entry the way
+// SearXNG does: every position p adds len(positions)/p, so results
+// found by more engines, nearer the top, score higher.
+func merge(results []result) []entry {
+	var entries []entry
+	seen := make(map[string]int)
+	for _, r := range results {
+		if i, ok := seen[r.URL]; ok && r.URL != "" {
+			entries[i].Engines = append(entries[i].Engines, r.Engine)
+			entries[i].Positions = append(entries[i].Positions, r.Rank)
+			if entries[i].Blurb == "" {
+				entries[i].Blurb = r.Blurb
+			}
+			continue
+		}
+		seen[r.URL] = len(entries)
+		entries = append(entries, entry{result: r, Engines: []string{r.Engine}, Positions: []int{r.Rank}})
+	}
+	for i := range entries {
+		for _, p := range entries[i].Positions {
+			if p > 0 {
+				entries[i].Score += float64(len(entries[i].Positions)) / float64(p)
+			}
+		}
+	}
+	return entries
+}
+
+// resultCache holds parsed results by search URL so repeated
+// searches within a session don't hit the search engines again.
+type resultCache struct {
+	mu      sync.Mutex
+	results map[string][]result
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{
+		results: make(map[string][]result),
+	}
+}
+
+func (c *resultCache) get(url string) ([]result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results, ok := c.results[url]
+	return results, ok
+}
+
+func (c *resultCache) put(url string, results []result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[url] = results
+}
+
+// dateRe matches the date many engines start a blurb with, e.g.
+// "Feb 1, 2023 — ", "1 Feb 2023 · ", or "3 days ago - ".
+var dateRe = regexp.MustCompile(`^((?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\.? \d{1,2}, \d{4}|\d{1,2} (?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]* \d{4}|\d{4}-\d{2}-\d{2}|\d+ (?:minute|hour|day|week|month|year)s? ago)\s*[-—·]\s*`)
+
+// leadingDate splits the date off the start of blurb, if it has one.
+func leadingDate(blurb string) (string, string) {
+	m := dateRe.FindStringSubmatch(blurb)
+	if m == nil {
+		return "", blurb
+	}
+	return m[1], blurb[len(m[0]):]
 }
 
 // cleanBlurb does a bit of tidying up of each input blurb string.
@@ -117,14 +314,38 @@ func (s *searcher) cleanLinks(str string) string {
 }
 
 // print truncates any blurb with a length longer
-// than s.length and prints to s.output.
-func (s *searcher) print(blurb, link string) {
+// than s.length and prints to s.output, highlighting
+// query terms when color is enabled. Images and videos
+// often come without a blurb, so their url, or the image's,
+// is always printed.
+func (s *searcher) print(r result) {
+	blurb := r.Blurb
 	if len(blurb) > s.length {
 		blurb = blurb[:s.length]
 	}
-	if s.urls && len(blurb) > 0 {
-		fmt.Fprintln(s.output, link)
+	if r.Title != "" {
+		fmt.Fprintf(s.output, "%s %s\n", s.highlight(r.Title), s.paint(ansiGreen, "["+r.Engine+"]"))
+	}
+	if d := r.details(); d != "" {
+		fmt.Fprintln(s.output, d)
+	}
+	media := s.vertical == "images" || s.vertical == "videos"
+	if s.urls && (len(blurb) > 0 || media) {
+		u := r.URL
+		if r.Image != "" {
+			u = r.Image
+		}
+		fmt.Fprintln(s.output, s.paint(ansiCyan, u))
+	}
+	if r.Check != nil {
+		fmt.Fprintln(s.output, s.checkLine(r.Check))
+	}
+	if blurb != "" {
+		fmt.Fprintln(s.output, s.highlight(blurb))
 	}
-	fmt.Fprintln(s.output, blurb)
 	fmt.Fprintln(s.output)
+	if r.Text != "" {
+		fmt.Fprintln(s.output, r.Text)
+		fmt.Fprintln(s.output)
+	}
 }
@@ -2,6 +2,7 @@ package search
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -11,6 +12,9 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	"github.com/davemolk/fuzzyHelpers"
 )
@@ -22,41 +26,95 @@ type searcher struct {
 	multiExact  bool
 	multi       bool
 	noTerms     bool
+	page        int
 	privacy     bool
+	queries     map[string]string
+	queryURLs   []string
 	search      string
 	terms       []string
 
+	// results
+	allow    *domainList
+	block    *domainList
+	boosts   map[string]float64
+	filtered int64
+	merged   bool
+
 	// requests
+	cache       *resultCache
+	check       bool
 	client      *http.Client
 	concurrency int
 	debug       bool
+	fetchPages  int
+	live        bool
 	osys        string
+	report      func(engine string, err error)
+	retryDelay  time.Duration
 	timeout     int
+	tokens      chan struct{}
+	// verified checks certificates, unlike client, and carries
+	// api keys and tokens
+	verified *http.Client
 
 	// output
-	length  int
-	noBlank *regexp.Regexp
-	urls    bool
+	color        bool
+	columns      []string
+	outputFormat string
+	interactive  bool
+	length       int
+	noBlank      *regexp.Regexp
+	termMatch    *regexp.Regexp
+	tmpl         *template.Template
+	urls         bool
 
 	// search engines
-	bing   *query
-	brave  *query
-	duck   *query
-	mojeek *query
-	qwant  *query
-	yahoo  *query
+	apiKeys         map[string]string
+	arxiv           *query
+	locale          locale
+	bases           map[string]string
+	engineNames     []string
+	bing            *query
+	brave           *query
+	crossref        *query
+	duck            *query
+	ecosia          *query
+	github          *query
+	githubCode      *query
+	hn              *query
+	mojeek          *query
+	pkgsite         *query
+	qwant           *query
+	searxng         *query
+	searxngDefault  bool
+	searxngURL      string
+	semanticScholar *query
+	stackoverflow   *query
+	since           string
+	startpage       *query
+	vertical        string
+	wikipedia       *query
+	wiktionary      *query
+	yahoo           *query
+
+	// hooks
+	exec    []string
+	webhook string
 
 	// other
-	input  io.Reader
-	output io.Writer
+	history *history
+	input   io.Reader
+	output  io.Writer
 }
 
 type option func(*searcher) error
 
 func NewSearcher(opts ...option) (*searcher, error) {
 	s := &searcher{
-		input:  os.Stdin,
-		output: os.Stdout,
+		input:      os.Stdin,
+		noBlank:    regexp.MustCompile(`\s{2,}`),
+		output:     os.Stdout,
+		retryDelay: time.Second,
 	}
 	for _, opt := range opts {
 		err := opt(s)
@@ -64,6 +122,10 @@ func NewSearcher(opts ...option) (*searcher, error) {
 			return &searcher{}, err
 		}
 	}
+	s.termMatch = s.termMatcher()
+	if s.tokens == nil {
+		s.tokens = make(chan struct{}, s.concurrency)
+	}
 	return s, nil
 }
 
@@ -87,6 +149,107 @@ func WithOutput(output io.Writer) option {
 	}
 }
 
+// WithClient sets the HTTP client used for requests, allowing
+// a single client to be shared across searches.
+func WithClient(client *http.Client) option {
+	return func(s *searcher) error {
+		if client == nil {
+			return fmt.Errorf("client is nil")
+		}
+		s.client = client
+		return nil
+	}
+}
+
+// WithVerifiedClient sets the HTTP client used for requests that
+// carry an api key or token. It should verify certificates.
+func WithVerifiedClient(client *http.Client) option {
+	return func(s *searcher) error {
+		if client == nil {
+			return fmt.Errorf("client is nil")
+		}
+		s.verified = client
+		return nil
+	}
+}
+
+// WithAPIKey searches the named search engine, brave or bing, with
+// its official api and key instead of by scraping, or, for github,
+// sets the token to search with. RunCLI reads keys from
+// $BRAVE_API_KEY, $BING_API_KEY, and $GITHUB_TOKEN.
+func WithAPIKey(engine, key string) option {
+	return func(s *searcher) error {
+		if _, ok := apiKeyEnv[engine]; !ok {
+			return fmt.Errorf("%w: got %q", ErrInvalidAPIKey, engine)
+		}
+		s.setAPIKey(engine, key)
+		return nil
+	}
+}
+
+func (s *searcher) setAPIKey(engine, key string) {
+	if s.apiKeys == nil {
+		s.apiKeys = make(map[string]string)
+	}
+	s.apiKeys[engine] = key
+}
+
+// WithBaseURL points the named search engine at base instead of
+// its usual URL, e.g. to query a mirror or a local stand-in. For an
+// engine with an api key (see WithAPIKey), base stands in for the
+// api and should serve its json.
+func WithBaseURL(engine, base string) option {
+	return func(s *searcher) error {
+		err := s.validateEngines(engine)
+		if err != nil {
+			return err
+		}
+		if s.bases == nil {
+			s.bases = make(map[string]string)
+		}
+		s.bases[engine] = base
+		return nil
+	}
+}
+
+// WithSearXNG sets the SearXNG instance searched with -engines
+// searxng. Unlike -searxng, it doesn't add the instance to the
+// default engines. RunCLI sets it from $SEARXNG_URL.
+func WithSearXNG(instance string) option {
+	return func(s *searcher) error {
+		err := s.validateSearXNG(instance)
+		if err != nil {
+			return err
+		}
+		s.searxngURL = instance
+		return nil
+	}
+}
+
+// withCache shares a cache of parsed results across searches.
+func withCache(c *resultCache) option {
+	return func(s *searcher) error {
+		s.cache = c
+		return nil
+	}
+}
+
+// withTokens shares a limit on concurrent requests across searches.
+func withTokens(tokens chan struct{}) option {
+	return func(s *searcher) error {
+		s.tokens = tokens
+		return nil
+	}
+}
+
+// withReport calls report with the outcome of each request.
+func withReport(report func(engine string, err error)) option {
+	return func(s *searcher) error {
+		s.report = report
+		return nil
+	}
+}
+
 var errHelp = errors.New(`usage:
 basic query info
 -m  include multiple terms within a single query
@@ -95,10 +258,58 @@ basic query info
 -n  no additional search terms
 	search -s foo => https://search.brave.com/search?q=foo, etc.
 	default: false
--p  privacy mode (when true, searches brave, duck duck go, mojeek, and qwant,
+-engines comma-separated search engines to query, overriding -p
+	arguments: arxiv, bing, brave, crossref, duck, ecosia, github, githubcode,
+	hn, mojeek, pkgsite, qwant, searxng, semanticscholar, stackoverflow,
+	startpage, wikipedia, wiktionary, yahoo
+	search -s foo -engines brave,mojeek
+	brave and bing are searched with their official apis when $BRAVE_API_KEY
+	or $BING_API_KEY is set, and scraped otherwise
+-group search a group of engines, along with any -engines
+	academic: arxiv, crossref, and semanticscholar, with authors, year, doi,
+	venue, and pdf link for each paper (see -o json and -o bibtex)
+	dev: github, stackoverflow, pkgsite (pkg.go.dev), and hn (hacker news),
+	and githubcode, which needs a token, when $GITHUB_TOKEN is set
+	reference: wikipedia and wiktionary, in the -lang edition
+	search -s "context cancel" -n -group dev
+-page results page to request from each search engine
+	default: 1
+-p  privacy mode (when true, searches brave, duck duck go, mojeek, qwant, and startpage,
 	otherwise, searches bing, duck duck go, brave, and yahoo)
 	default: true
 -s  base search term(s)
+-searxng url of your own SearXNG instance, searched with its json api
+	along with the other engines (or alone, with -engines searxng)
+	$SEARXNG_URL sets the instance for -engines searxng only
+-region two letter country code to get results for, e.g. us, gb, or de
+	(ecosia, and startpage beyond -safe, don't take any of these)
+	search -s foo -region de -lang en
+-lang two letter language code for results, also sent as Accept-Language,
+	and the edition of wikipedia and wiktionary to search (english without it)
+	default: from -region, if set, otherwise left to each engine
+-safe safe search: off, moderate, or strict
+	default: left to each engine
+-since only find results from the past day, week, month, or year
+	(bing and yahoo don't go back a year, and mojeek, qwant, and ecosia
+	can't limit results at all, so they search all time)
+	search -s golang -n -since week
+-type web, news, images, or videos, searched with the engines that have them
+	(bing, brave, mojeek, and yahoo for news, bing for images and videos, and
+	searxng and the apis for all three), skipping the rest
+	search -s golang -n -type news
+	default: web
+
+
+results
+-allow only keep results from these domains (see -block)
+-block drop results from these domains: a comma-separated list of domains,
+	which include their subdomains, and globs, with @file reading one per line
+	(plain, hosts file, adblock ||domain^, and uBlacklist *://*.domain/* lists work)
+	search -s foo -block pinterest.com,*.quora.com,@blocklist.txt
+-boost multiply the score of results from these domains (requires -merge)
+	search -s foo -merge -boost go.dev=2,medium.com=0.5
+-merge combine results that share a url and sort them by score
+	default: false
 
 
 exact searching
@@ -116,6 +327,13 @@ exact searching
 requests
 -c  max number of concurrent requests
 	default: 10
+-check request each result url and print its status, redirects, and content type
+	default: false
+-fetch download the N best ranked result urls, once every engine has answered,
+	and print their readable text
+	default: 0
+-live with -check, drop results whose url fails or returns a 4xx or 5xx
+	default: false
 -os operating system (used for creating browser headers)
 	arguments: any, l, m, or w
 	default: w
@@ -123,12 +341,51 @@ requests
 	default: 5000
 
 output
+-format template for each result, using go's text/template, with the
+	result's fields (.Query, .Engine, .Rank, .Title, .URL, .Blurb) and
+	truncate, domain, highlight, and join
+	search -s foo -format '{{.Rank}}. {{.Title}} <{{.URL}}>'
+-i  browse results in an interactive terminal ui
+	default: false
+-color highlight query terms, engine names, and urls
+	arguments: auto, always, or never (auto respects NO_COLOR)
+	default: auto
+-columns columns for csv and tsv output, in order
+	arguments: query, engine, rank, title, url, blurb, and for -type,
+	date, source, image, thumbnail, width, height, duration
+	and for -group academic, authors, year, doi, venue, pdf
+	default: query,engine,rank,title,url,blurb
 -l  length of result summary
 	default: 500
+-o  output format (markdown and html write a report grouped by query,
+	json writes one array of every result, and bibtex writes an entry
+	per paper, combining results for the same paper from several engines)
+	arguments: text, csv, tsv, markdown, html, json, or bibtex
+	default: text
+-template like -format, from a file, which may also define "header" and
+	"footer" templates to write before and after each query's results
+	(given .Query and .Results)
 -u  include result urls in output
 	default: true
 
 	
+hooks
+-exec run a command for each result, replacing {url}, {title}, {blurb},
+	{engine}, {query}, and {rank} in its arguments, which are also set as
+	$SEARCH_URL, etc. (the command's output goes to stderr)
+	search -s foo -exec 'notify-send {title} {url}'
+-webhook post results as json to this url, in batches of 100
+	(failed commands and posts are retried twice before being reported)
+
+
+history
+-history save results to the history, listed with search history
+	(search history grep <words> finds results with every word, and word*
+	matches words it starts), $SEARCH_HISTORY sets the file, and
+	-history=false skips saving a run
+	default: true
+
+
 help
 -d  print the search url to help debug queries
 	default: false
@@ -139,20 +396,47 @@ func FromArgs(args []string) option {
 		fset := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 		// query
 		multi := fset.Bool("m", false, "multiple terms")
+		engines := fset.String("engines", "", "comma-separated search engines")
+		group := fset.String("group", "", "group of search engines")
 		noTerms := fset.Bool("n", false, "no additional search terms")
+		page := fset.Int("page", 1, "results page")
 		privacy := fset.Bool("p", true, "privacy mode")
+		searxngURL := fset.String("searxng", "", "url of a searxng instance")
 		search := fset.String("s", "", "base search term(s)")
+		region := fset.String("region", "", "two letter country code")
+		lang := fset.String("lang", "", "two letter language code")
+		safe := fset.String("safe", "", "off, moderate, or strict")
+		since := fset.String("since", "", "day, week, month, or year")
+		vertical := fset.String("type", "web", "web, news, images, or videos")
 		// exact searching
 		exact := fset.Bool("e", false, "exact matching")
 		multiExact := fset.Bool("me", false, "exact matching for multiple additional terms")
 		searchExact := fset.Bool("se", false, "exact matching for base search term(s)")
+		// results
+		allow := fset.String("allow", "", "only keep results from these domains")
+		block := fset.String("block", "", "drop results from these domains")
+		boost := fset.String("boost", "", "domain=factor score boosts for -merge")
+		merged := fset.Bool("merge", false, "merge and rank results")
 		//requests
 		concurrency := fset.Int("c", 10, "max number of concurrent requests")
+		check := fset.Bool("check", false, "check result urls")
+		fetchPages := fset.Int("fetch", 0, "download the top N result urls")
+		live := fset.Bool("live", false, "drop dead links")
 		osys := fset.String("os", "w", "l, m, or w")
 		to := fset.Int("t", 5000, "timeout in ms")
 		// output
+		color := fset.String("color", "auto", "auto, always, or never")
+		interactive := fset.Bool("i", false, "interactive terminal ui")
 		length := fset.Int("l", 500, "length of blurb")
+		format := fset.String("o", "text", "text, csv, tsv, markdown, html, json, or bibtex")
+		tmplFormat := fset.String("format", "", "template for each result")
+		tmplFile := fset.String("template", "", "template file")
+		columns := fset.String("columns", strings.Join(csvColumns, ","), "columns for csv and tsv")
 		urls := fset.Bool("u", true, "print urls")
+		hist := fset.Bool("history", true, "save results to the history")
+		// hooks
+		execCmd := fset.String("exec", "", "command to run for each result")
+		webhook := fset.String("webhook", "", "url to post results to")
 		// help
 		debug := fset.Bool("d", false, "print the search url to help debug queries")
 		help := fset.Bool("h", false, "")
@@ -175,23 +459,155 @@ func FromArgs(args []string) option {
 		if err != nil {
 			return err
 		}
+		err = s.validateColor(*color)
+		if err != nil {
+			return err
+		}
+		err = s.validatePage(*page)
+		if err != nil {
+			return err
+		}
+		err = s.validateFormat(*format)
+		if err != nil {
+			return err
+		}
+		if *tmplFormat != "" || *tmplFile != "" {
+			if *format != "text" {
+				return fmt.Errorf("%w: -format and -template only work with -o text", ErrInvalidTemplate)
+			}
+			s.tmpl, err = s.parseTemplate(*tmplFormat, *tmplFile)
+			if err != nil {
+				return err
+			}
+		}
+		s.columns = strings.Split(*columns, ",")
+		err = s.validateColumns(s.columns...)
+		if err != nil {
+			return err
+		}
+		err = s.validateFetch(*fetchPages)
+		if err != nil {
+			return err
+		}
+		if *allow != "" {
+			s.allow, err = parseDomains(*allow)
+			if err != nil {
+				return err
+			}
+		}
+		if *block != "" {
+			s.block, err = parseDomains(*block)
+			if err != nil {
+				return err
+			}
+		}
+		if *boost != "" {
+			if !*merged {
+				return fmt.Errorf("%w: -boost requires -merge", ErrInvalidBoost)
+			}
+			s.boosts, err = parseBoosts(*boost)
+			if err != nil {
+				return err
+			}
+		}
+		err = s.validateWebhook(*webhook)
+		if err != nil {
+			return err
+		}
+		if *execCmd != "" {
+			s.exec, err = splitArgs(*execCmd)
+			if err != nil || len(s.exec) == 0 {
+				return fmt.Errorf("%w: got %q", ErrInvalidExec, *execCmd)
+			}
+		}
+		if *hist {
+			// the file is found when there's a run to save, so
+			// searches that don't save one work without it
+			s.history = &history{}
+		}
+		if *group != "" {
+			err = s.validateGroup(*group)
+			if err != nil {
+				return err
+			}
+			s.engineNames = s.groupEngines(*group)
+		}
+		if *engines != "" {
+			names := strings.Split(*engines, ",")
+			err = s.validateEngines(names...)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if !contains(s.engineNames, name) {
+					s.engineNames = append(s.engineNames, name)
+				}
+			}
+		}
+		if contains(s.engineNames, "githubcode") && s.apiKeys["github"] == "" && s.bases["githubcode"] == "" {
+			return fmt.Errorf("%w: githubcode needs $GITHUB_TOKEN", ErrInvalidAPIKey)
+		}
+		err = s.validateSearXNG(*searxngURL)
+		if err != nil {
+			return err
+		}
+		if *searxngURL != "" {
+			s.searxngURL = *searxngURL
+			s.searxngDefault = true
+		}
+		err = s.validateType(*vertical)
+		if err != nil {
+			return err
+		}
+		err = s.validateSince(*since)
+		if err != nil {
+			return err
+		}
+		s.locale = locale{
+			region: strings.ToLower(*region),
+			lang:   strings.ToLower(*lang),
+			safe:   *safe,
+		}
+		err = s.validateLocale(s.locale)
+		if err != nil {
+			return err
+		}
 
+		s.check = *check || *live
+		s.color = s.useColor(*color)
 		s.concurrency = *concurrency
 		s.debug = *debug
 		s.exact = *exact
+		s.fetchPages = *fetchPages
+		s.outputFormat = *format
+		s.interactive = *interactive
 		s.length = *length
+		s.live = *live
+		s.merged = *merged
 		s.multi = *multi
 		s.multiExact = *multiExact
 		s.noTerms = *noTerms
 		s.osys = *osys
+		s.page = *page - 1
 		s.privacy = *privacy
 		s.search = *search
 		s.searchExact = *searchExact
+		s.since = *since
+		s.vertical = *vertical
 		s.timeout = *to
 		s.urls = *urls
-		s.client = fuzzyHelpers.NewClient(
-			fuzzyHelpers.WithConnections(s.concurrency),
-		)
+		s.webhook = *webhook
+		if s.client == nil {
+			s.client = fuzzyHelpers.NewClient(
+				fuzzyHelpers.WithConnections(s.concurrency),
+			)
+		}
+		if s.verified == nil {
+			s.verified = fuzzyHelpers.NewClient(
+				fuzzyHelpers.WithConnections(s.concurrency),
+				fuzzyHelpers.WithNoSkip(true),
+			)
+		}
 
 		// no additional search terms
 		if s.noTerms {
@@ -238,34 +654,160 @@ func (s *searcher) readTerms() error {
 	return scan.Err()
 }
 
+// envOptions returns the options RunCLI takes from the environment,
+// so NewSearcher and FromArgs never read it themselves: api keys from
+// $BRAVE_API_KEY, $BING_API_KEY, and $GITHUB_TOKEN, and the SearXNG
+// instance from $SEARXNG_URL.
+func envOptions() []option {
+	var opts []option
+	if instance := os.Getenv("SEARXNG_URL"); instance != "" {
+		opts = append(opts, WithSearXNG(instance))
+	}
+	for engine, env := range apiKeyEnv {
+		if key := os.Getenv(env); key != "" {
+			opts = append(opts, WithAPIKey(engine, key))
+		}
+	}
+	return opts
+}
+
 func RunCLI() {
-	s, err := NewSearcher(
+	env := envOptions()
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "repl":
+			run = func(args []string) error {
+				return runREPL(args, os.Stdin, os.Stdout, env...)
+			}
+		case "serve":
+			run = func(args []string) error {
+				return runServe(args, env...)
+			}
+		case "opensearch":
+			run = func(args []string) error {
+				return runOpenSearch(args, env...)
+			}
+		case "searxng":
+			run = func(args []string) error {
+				return runSearXNG(args, env...)
+			}
+		case "mcp":
+			run = func(args []string) error {
+				return runMCP(args, env...)
+			}
+		case "watch":
+			run = func(args []string) error {
+				return runWatch(args, os.Stdout, env...)
+			}
+		case "history":
+			run = func(args []string) error {
+				return runHistory(args, os.Stdout)
+			}
+		}
+		if run != nil {
+			err := run(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	s, err := NewSearcher(append(env,
 		FromArgs(os.Args[1:]),
-	)
+	)...)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	s.noBlank = regexp.MustCompile(`\s{2,}`)
 	s.CreateQueries()
-	ch := s.FormatURL()
-
-	tokens := make(chan struct{}, s.concurrency)
-	var wg sync.WaitGroup
-	for c := range ch {
-		wg.Add(1)
-		tokens <- struct{}{}
-		go func(c string) {
-			defer wg.Done()
-			defer func() { <-tokens }()
-			s.Search(c)
-		}(c)
-		if s.debug {
-			fmt.Println("*****")
-			fmt.Println("query:", c)
-			fmt.Println("*****")
-			fmt.Println()
+	for _, name := range s.skipped() {
+		fmt.Fprintf(os.Stderr, "%s has no %s search, skipping\n", name, s.vertical)
+	}
+	for _, name := range s.unlimited() {
+		fmt.Fprintf(os.Stderr, "%s can't limit results to the past %s, searching all time\n", name, s.since)
+	}
+	results := s.results(context.Background())
+
+	if s.interactive {
+		err = s.browse(results)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		return
+	}
+	all, n, err := s.write(results)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	s.record(all)
+	for _, err := range s.deliver(context.Background(), all) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if s.filtering() {
+		fmt.Fprintf(os.Stderr, "%d results, %d filtered\n", n, atomic.LoadInt64(&s.filtered))
 	}
-	wg.Wait()
+}
+
+// results searches every engine for every term, passing the
+// results through -check and -fetch when they're set.
+func (s *searcher) results(ctx context.Context) <-chan result {
+	results := s.stream(ctx, s.FormatURL())
+	if s.check {
+		results = s.checkLinks(ctx, results)
+	}
+	if s.fetchPages > 0 {
+		results = s.readPages(ctx, results)
+	}
+	return results
+}
+
+// stream fetches every url from ch, limited to s.concurrency
+// requests at a time, and sends each parsed result on the
+// returned channel, which is closed once all requests finish
+// or ctx is done.
+func (s *searcher) stream(ctx context.Context, ch <-chan string) <-chan result {
+	out := make(chan result)
+	go func() {
+		defer close(out)
+		tokens := s.tokens
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		for c := range ch {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(c string) {
+				defer wg.Done()
+				defer func() { <-tokens }()
+				results, err := s.fetch(ctx, c)
+				if err != nil {
+					if s.debug {
+						fmt.Fprintln(os.Stderr, err)
+					}
+					return
+				}
+				for _, r := range results {
+					select {
+					case out <- r:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}(c)
+			if s.debug && !s.interactive {
+				fmt.Fprintln(os.Stderr, "*****")
+				fmt.Fprintln(os.Stderr, "query:", c)
+				fmt.Fprintln(os.Stderr, "*****")
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}()
+	return out
 }
@@ -0,0 +1,316 @@
+package search
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// searxngNames maps engine names that differ between SearXNG and
+// this package. Any other name is the same in both.
+var searxngNames = map[string]string{
+	"duck":            "duckduckgo",
+	"githubcode":      "github code",
+	"hn":              "hackernews",
+	"pkgsite":         "pkg.go.dev",
+	"semanticscholar": "semantic scholar",
+}
+
+// searxngCategories are the SearXNG categories we can answer: web
+// search as general, the -type verticals, and the -group groups in
+// searxngGroups.
+var searxngCategories = []string{"general", "news", "images", "videos", "science", "it"}
+
+// searxngGroups maps SearXNG categories to the -group searching them.
+var searxngGroups = map[string]string{
+	"science": "academic",
+	"it":      "dev",
+}
+
+// searxng mimics the SearXNG search api, so existing SearXNG clients
+// and browser extensions can use this package's engines.
+type searxng struct {
+	*server
+}
+
+// NewSearXNG returns an http.Handler serving a SearXNG compatible
+// /search and /config. args and opts work as they do for NewServer,
+// and results are always merged.
+func NewSearXNG(args []string, timeout time.Duration, opts ...option) (*searxng, error) {
+	sv, err := NewServer(append([]string{"-merge"}, args...), timeout, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &searxng{server: sv}, nil
+}
+
+func (sx *searxng) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/search", "/":
+		sx.handleSearch(w, r)
+	case "/config":
+		sx.handleConfig(w, r)
+	case "/healthz":
+		sx.handleHealth(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// searxngResult is a single result in the SearXNG response shape.
+type searxngResult struct {
+	URL       string   `json:"url"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Engine    string   `json:"engine"`
+	Engines   []string `json:"engines"`
+	Positions []int    `json:"positions"`
+	Score     float64  `json:"score"`
+	Category  string   `json:"category"`
+	ParsedURL []string `json:"parsed_url"`
+	Template  string   `json:"template"`
+}
+
+// searxngResponse is the body of a SearXNG /search?format=json
+// response.
+type searxngResponse struct {
+	Query               string          `json:"query"`
+	NumberOfResults     int             `json:"number_of_results"`
+	Results             []searxngResult `json:"results"`
+	Answers             []string        `json:"answers"`
+	Corrections         []string        `json:"corrections"`
+	Infoboxes           []any           `json:"infoboxes"`
+	Suggestions         []string        `json:"suggestions"`
+	UnresponsiveEngines [][]string      `json:"unresponsive_engines"`
+}
+
+// handleSearch answers q, categories, engines, and pageno the way
+// SearXNG does, from either the query string or a posted form.
+func (sx *searxng) handleSearch(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if format := r.Form.Get("format"); format != "" && format != "json" {
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: "only format=json is supported"})
+		return
+	}
+	q := strings.TrimSpace(r.Form.Get("q"))
+	resp := searxngResponse{
+		Query:               q,
+		Results:             []searxngResult{},
+		Answers:             []string{},
+		Corrections:         []string{},
+		Infoboxes:           []any{},
+		Suggestions:         []string{},
+		UnresponsiveEngines: [][]string{},
+	}
+	category, ok := sx.category(r.Form.Get("categories"))
+	if !ok {
+		// nothing we can search, but not an error to a client
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	params := url.Values{"q": {q}}
+	if category != sx.defaultCategory() {
+		if group, ok := searxngGroups[category]; ok {
+			params.Set("group", group)
+		} else if category == "general" {
+			params.Set("type", "web")
+		} else {
+			params.Set("type", category)
+		}
+	}
+	if engines := r.Form.Get("engines"); engines != "" {
+		names := fromSearXNGNames(engines)
+		if len(names) == 0 {
+			// none of the requested engines are ours
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		params.Set("engines", strings.Join(names, ","))
+	}
+	if pageno := r.Form.Get("pageno"); pageno != "" {
+		params.Set("page", pageno)
+	}
+	s, err := sx.searcher(params)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	_, results, failures := sx.run(r.Context(), s)
+	resp.Results = searxngResults(s.rank(merge(results)), category)
+	resp.NumberOfResults = len(resp.Results)
+	for engine, msg := range failures {
+		resp.UnresponsiveEngines = append(resp.UnresponsiveEngines, []string{toSearXNGName(engine), msg})
+	}
+	sort.Slice(resp.UnresponsiveEngines, func(i, j int) bool {
+		return resp.UnresponsiveEngines[i][0] < resp.UnresponsiveEngines[j][0]
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// searxngResults converts ranked entries in category to SearXNG
+// results.
+func searxngResults(entries []entry, category string) []searxngResult {
+	out := []searxngResult{}
+	for _, e := range entries {
+		var engines []string
+		for _, name := range e.Engines {
+			engines = append(engines, toSearXNGName(name))
+		}
+		out = append(out, searxngResult{
+			URL:       e.URL,
+			Title:     e.Title,
+			Content:   e.Blurb,
+			Engine:    engines[0],
+			Engines:   engines,
+			Positions: e.Positions,
+			Score:     e.Score,
+			Category:  category,
+			ParsedURL: parsedURL(e.URL),
+			Template:  "default.html",
+		})
+	}
+	return out
+}
+
+// parsedURL splits u like python's urlparse, which SearXNG uses.
+func parsedURL(u string) []string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return []string{"", "", "", "", "", ""}
+	}
+	return []string{p.Scheme, p.Host, p.Path, "", p.RawQuery, p.Fragment}
+}
+
+// category picks the category to search from categories, a
+// comma-separated list, preferring the default, or reports false
+// when there's none we can search.
+func (sx *searxng) category(categories string) (string, bool) {
+	def := sx.defaultCategory()
+	if categories == "" {
+		return def, true
+	}
+	var found string
+	for _, c := range strings.Split(categories, ",") {
+		c = strings.TrimSpace(c)
+		if c == def {
+			return c, true
+		}
+		if found == "" && contains(searxngCategories, c) {
+			found = c
+		}
+	}
+	return found, found != ""
+}
+
+// defaultCategory is the category searched without asking for one:
+// the server's -type, or science or it when every engine is in that
+// group, and otherwise general.
+func (sx *searxng) defaultCategory() string {
+	if sx.base.vertical != "" && sx.base.vertical != "web" {
+		return sx.base.vertical
+	}
+	var category string
+	for _, e := range sx.engines() {
+		c := sx.engineCategories(e.name)[0]
+		if category != "" && c != category {
+			return "general"
+		}
+		category = c
+	}
+	if category == "" {
+		return "general"
+	}
+	return category
+}
+
+// engineCategories returns the categories the engine called name can
+// search: science or it for the academic and dev groups, and
+// otherwise general, along with the verticals it has.
+func (sx *searxng) engineCategories(name string) []string {
+	for category, group := range searxngGroups {
+		// githubcode joins dev whenever there's a token
+		if contains(engineGroups[group], name) || (group == "dev" && name == "githubcode") {
+			return []string{category}
+		}
+	}
+	categories := []string{"general"}
+	for _, v := range verticals {
+		if _, ok := sx.base.queriesFor(v)[name]; ok {
+			categories = append(categories, v)
+		}
+	}
+	return categories
+}
+
+func toSearXNGName(engine string) string {
+	if n, ok := searxngNames[engine]; ok {
+		return n
+	}
+	return engine
+}
+
+// fromSearXNGNames converts a comma-separated list of SearXNG engine
+// names to ours, dropping any engine we don't have, as SearXNG does.
+func fromSearXNGNames(engines string) []string {
+	var names []string
+	for _, n := range strings.Split(engines, ",") {
+		n = strings.TrimSpace(n)
+		for ours, theirs := range searxngNames {
+			if n == theirs {
+				n = ours
+			}
+		}
+		if contains(engineNames, n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// handleConfig answers a trimmed down SearXNG /config, enough for
+// clients that list the available engines and categories.
+func (sx *searxng) handleConfig(w http.ResponseWriter, r *http.Request) {
+	type engine struct {
+		Name       string   `json:"name"`
+		Categories []string `json:"categories"`
+		Enabled    bool     `json:"enabled"`
+		Paging     bool     `json:"paging"`
+	}
+	enabled := make(map[string]bool)
+	for _, e := range sx.engines() {
+		enabled[e.name] = true
+	}
+	var engines []engine
+	for _, name := range engineNames {
+		engines = append(engines, engine{
+			Name:       toSearXNGName(name),
+			Categories: sx.engineCategories(name),
+			Enabled:    enabled[name],
+			Paging:     true,
+		})
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Categories []string `json:"categories"`
+		Engines    []engine `json:"engines"`
+		Instance   string   `json:"instance_name"`
+	}{
+		Categories: searxngCategories,
+		Engines:    engines,
+		Instance:   "search",
+	})
+}
+
+// runSearXNG serves the SearXNG compatible api until the listener
+// fails.
+func runSearXNG(args []string, opts ...option) error {
+	return runHTTP("searxng", args, func(args []string, timeout time.Duration) (http.Handler, error) {
+		return NewSearXNG(args, timeout, opts...)
+	})
+}
@@ -0,0 +1,228 @@
+package search_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+type searxngResponse struct {
+	Query           string `json:"query"`
+	NumberOfResults int    `json:"number_of_results"`
+	Results         []struct {
+		URL       string   `json:"url"`
+		Title     string   `json:"title"`
+		Content   string   `json:"content"`
+		Engine    string   `json:"engine"`
+		Engines   []string `json:"engines"`
+		Positions []int    `json:"positions"`
+		Score     float64  `json:"score"`
+		Category  string   `json:"category"`
+		ParsedURL []string `json:"parsed_url"`
+	} `json:"results"`
+	UnresponsiveEngines [][]string `json:"unresponsive_engines"`
+}
+
+func searxngSearch(t *testing.T, h http.Handler, req *http.Request) searxngResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	var resp searxngResponse
+	err := json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestSearXNGSearch(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	sx, err := search.NewSearXNG(nil, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/search?q=golang&format=json&engines=brave,mojeek,google", nil)
+	resp := searxngSearch(t, sx, req)
+	if resp.Query != "golang" || resp.NumberOfResults != 3 {
+		t.Fatalf("got query %q with %d results", resp.Query, resp.NumberOfResults)
+	}
+	top := resp.Results[0]
+	if top.URL != "https://go.dev/" || top.Score != 4 || !reflect.DeepEqual(top.Positions, []int{1, 1}) {
+		t.Errorf("want go.dev from both engines on top, got %+v", top)
+	}
+	if len(top.Engines) != 2 || top.Category != "general" {
+		t.Errorf("got engines %v category %q", top.Engines, top.Category)
+	}
+	wantParsed := []string{"https", "go.dev", "/", "", "", ""}
+	if !reflect.DeepEqual(top.ParsedURL, wantParsed) {
+		t.Errorf("got parsed_url %v want %v", top.ParsedURL, wantParsed)
+	}
+	for _, r := range resp.Results[1:] {
+		if r.Score != 0.5 {
+			t.Errorf("%s: got score %v want 0.5", r.URL, r.Score)
+		}
+	}
+}
+
+func TestSearXNGSearchForm(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t, "duck")
+	sx, err := search.NewSearXNG(nil, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"q": {"golang"}, "engines": {"duckduckgo,brave"}, "pageno": {"1"}}
+	req := httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := searxngSearch(t, sx, req)
+	if len(resp.Results) != 2 {
+		t.Errorf("got %d results want 2", len(resp.Results))
+	}
+	if len(resp.UnresponsiveEngines) != 1 || resp.UnresponsiveEngines[0][0] != "duckduckgo" {
+		t.Errorf("want duckduckgo unresponsive, got %v", resp.UnresponsiveEngines)
+	}
+}
+
+func TestSearXNGUnsupported(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	sx, err := search.NewSearXNG(nil, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{
+		"/search?q=golang&categories=music",
+		"/search?q=golang&engines=google",
+	} {
+		resp := searxngSearch(t, sx, httptest.NewRequest(http.MethodGet, target, nil))
+		if resp.Results == nil || len(resp.Results) != 0 {
+			t.Errorf("%s: want empty results, got %v", target, resp.Results)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	sx.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&format=rss", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestSearXNGConfig(t *testing.T) {
+	t.Parallel()
+	sx, err := search.NewSearXNG([]string{"-engines", "duck,mojeek"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sx.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	var config struct {
+		Engines []struct {
+			Name    string `json:"name"`
+			Enabled bool   `json:"enabled"`
+		} `json:"engines"`
+	}
+	err = json.NewDecoder(rec.Body).Decode(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var enabled []string
+	for _, e := range config.Engines {
+		if e.Enabled {
+			enabled = append(enabled, e.Name)
+		}
+	}
+	if want := []string{"duckduckgo", "mojeek"}; !reflect.DeepEqual(enabled, want) {
+		t.Errorf("got enabled %v want %v", enabled, want)
+	}
+}
+
+func TestSearXNGConfigCategories(t *testing.T) {
+	t.Parallel()
+	sx, err := search.NewSearXNG(nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sx.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	var config struct {
+		Engines []struct {
+			Name       string   `json:"name"`
+			Categories []string `json:"categories"`
+		} `json:"engines"`
+	}
+	err = json.NewDecoder(rec.Body).Decode(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, e := range config.Engines {
+		got[e.Name] = e.Categories
+	}
+	want := map[string][]string{
+		"bing":             {"general", "news", "images", "videos"},
+		"duckduckgo":       {"general"},
+		"github code":      {"it"},
+		"hackernews":       {"it"},
+		"pkg.go.dev":       {"it"},
+		"semantic scholar": {"science"},
+		"stackoverflow":    {"it"},
+		"wikipedia":        {"general"},
+	}
+	for name, categories := range want {
+		if !reflect.DeepEqual(got[name], categories) {
+			t.Errorf("%s: got categories %v want %v", name, got[name], categories)
+		}
+	}
+}
+
+func TestSearXNGCategories(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	tests := []struct {
+		args     []string
+		target   string
+		category string
+		engines  []string
+	}{
+		{nil, "/search?q=golang&categories=science", "science", []string{"arxiv", "crossref", "semantic scholar"}},
+		{nil, "/search?q=golang&categories=it&engines=hackernews", "it", []string{"github", "hackernews", "pkg.go.dev", "stackoverflow"}},
+		// the server's group is the default
+		{[]string{"-group", "academic"}, "/search?q=golang", "science", []string{"arxiv", "crossref", "semantic scholar"}},
+	}
+	for _, tt := range tests {
+		sx, err := search.NewSearXNG(tt.args, time.Second, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := searxngSearch(t, sx, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		engines := make(map[string]bool)
+		for _, r := range resp.Results {
+			if r.Category != tt.category {
+				t.Errorf("%s: got category %q want %q", tt.target, r.Category, tt.category)
+			}
+			for _, e := range r.Engines {
+				engines[e] = true
+			}
+		}
+		var got []string
+		for e := range engines {
+			got = append(got, e)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.engines) {
+			t.Errorf("%s: got engines %v want %v", tt.target, got, tt.engines)
+		}
+	}
+}
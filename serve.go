@@ -0,0 +1,349 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// server exposes the search fan-out over HTTP. Every request gets
+// its own searcher, but all of them share the same clients, one
+// limit on concurrent requests, and one record of engine health.
+type server struct {
+	args     []string
+	base     *searcher
+	client   *http.Client
+	health   *engineHealth
+	opts     []option
+	timeout  time.Duration
+	tokens   chan struct{}
+	verified *http.Client
+}
+
+// NewServer returns an http.Handler serving /search and /healthz.
+// args are the usual command line flags (e.g. -c, -t, -os, -p) and
+// apply to every request; opts are applied to every searcher.
+func NewServer(args []string, timeout time.Duration, opts ...option) (*server, error) {
+	// validate flags up front, and keep the resulting client
+	base, err := NewSearcher(append(append([]option{}, opts...),
+		WithOutput(io.Discard),
+		FromArgs(append(append([]string{}, args...), "-s", "serve", "-n")),
+	)...)
+	if err != nil {
+		return nil, err
+	}
+	base.CreateQueries()
+	return &server{
+		args:     args,
+		base:     base,
+		client:   base.client,
+		health:   newEngineHealth(),
+		opts:     opts,
+		timeout:  timeout,
+		tokens:   base.tokens,
+		verified: base.verified,
+	}, nil
+}
+
+func (sv *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/search":
+		sv.handleSearch(w, r)
+	case "/healthz":
+		sv.handleHealth(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// searchResponse is the body of a JSON /search response.
+type searchResponse struct {
+	Query   string            `json:"query"`
+	URLs    []string          `json:"urls"`
+	Results []result          `json:"results"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// errorResponse is the body of a JSON error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handleSearch runs a search for the q parameter, with optional
+// repeated term parameters, engines, page, and format (json or text).
+func (sv *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "text" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "format must be json or text"})
+		return
+	}
+
+	s, err := sv.searcher(q)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	urls, results, failures := sv.run(r.Context(), s)
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		s.output = w
+		for _, res := range results {
+			s.print(res)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, searchResponse{
+		Query:   strings.ReplaceAll(s.search, "+", " "),
+		URLs:    urls,
+		Results: results,
+		Errors:  failures,
+	})
+}
+
+// run fans out s's queries, giving up after the server timeout,
+// and returns the search URLs, every result, and the error from
+// any engine that failed.
+func (sv *server) run(ctx context.Context, s *searcher) ([]string, []result, map[string]string) {
+	ctx, cancel := context.WithTimeout(ctx, sv.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	failures := make(map[string]string)
+	report := s.report
+	s.report = func(engine string, err error) {
+		if report != nil {
+			report(engine, err)
+		}
+		if err != nil {
+			mu.Lock()
+			failures[engine] = err.Error()
+			mu.Unlock()
+		}
+	}
+
+	var urls []string
+	for u := range s.FormatURL() {
+		urls = append(urls, u)
+	}
+	results := []result{}
+	for res := range s.stream(ctx, sliceChan(urls)) {
+		results = append(results, res)
+	}
+	return urls, results, failures
+}
+
+// searcher builds a searcher for one request from the server flags
+// and the query parameters.
+func (sv *server) searcher(q map[string][]string) (*searcher, error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	args := append([]string{}, sv.args...)
+	if engines := get("engines"); engines != "" {
+		args = append(args, "-engines", engines)
+	}
+	if page := get("page"); page != "" {
+		args = append(args, "-page", page)
+	}
+	if vertical := get("type"); vertical != "" {
+		args = append(args, "-type", vertical)
+	}
+	if group := get("group"); group != "" {
+		args = append(args, "-group", group)
+	}
+	args = append(args, "-s", get("q"))
+	terms := q["term"]
+	if len(terms) == 0 {
+		args = append(args, "-n")
+	}
+	args = append(append(args, "--"), terms...)
+
+	s, err := NewSearcher(append(append([]option{}, sv.opts...),
+		WithClient(sv.client),
+		WithVerifiedClient(sv.verified),
+		WithInput(strings.NewReader("")),
+		WithOutput(io.Discard),
+		withReport(sv.health.record),
+		withTokens(sv.tokens),
+		FromArgs(args),
+	)...)
+	if err != nil {
+		return nil, err
+	}
+	s.CreateQueries()
+	return s, nil
+}
+
+// engines returns the engines a request without an engines
+// parameter will query.
+func (sv *server) engines() []*query {
+	return sv.base.engines()
+}
+
+// handleHealth reports the outcome of the most recent request to
+// each search engine.
+func (sv *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Status  string                  `json:"status"`
+		Engines map[string]engineStatus `json:"engines"`
+	}{
+		Status:  "ok",
+		Engines: sv.health.snapshot(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// sliceChan sends each string in list on the returned channel.
+func sliceChan(list []string) <-chan string {
+	out := make(chan string, len(list))
+	for _, l := range list {
+		out <- l
+	}
+	close(out)
+	return out
+}
+
+// engineStatus is the outcome of the latest request to an engine.
+type engineStatus struct {
+	Status      string     `json:"status"`
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	LastOK      *time.Time `json:"last_ok,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// engineHealth records engine status across requests.
+type engineHealth struct {
+	mu      sync.Mutex
+	engines map[string]engineStatus
+}
+
+func newEngineHealth() *engineHealth {
+	h := &engineHealth{
+		engines: make(map[string]engineStatus),
+	}
+	for _, name := range engineNames {
+		h.engines[name] = engineStatus{Status: "unknown"}
+	}
+	return h
+}
+
+func (h *engineHealth) record(engine string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.engines[engine]
+	now := time.Now()
+	st.LastChecked = &now
+	if err != nil {
+		st.Status = "failing"
+		st.Error = err.Error()
+	} else {
+		st.Status = "ok"
+		st.LastOK = &now
+		st.Error = ""
+	}
+	h.engines[engine] = st
+}
+
+func (h *engineHealth) snapshot() map[string]engineStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string]engineStatus, len(h.engines))
+	for k, v := range h.engines {
+		out[k] = v
+	}
+	return out
+}
+
+// runServe serves the search api until the listener fails.
+func runServe(args []string, opts ...option) error {
+	return runHTTP("serve", args, func(args []string, timeout time.Duration) (http.Handler, error) {
+		return NewServer(args, timeout, opts...)
+	})
+}
+
+// runHTTP parses the flags shared by the http modes (-addr and -rt)
+// and serves the handler from newHandler until the listener fails.
+// Any other flags are passed to newHandler.
+func runHTTP(name string, args []string, newHandler func([]string, time.Duration) (http.Handler, error)) error {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	addr := fset.String("addr", ":8080", "address to listen on")
+	rt := fset.Int("rt", 10000, "timeout for each search request, in ms")
+	own, rest := splitFlags(args, "addr", "rt")
+	err := fset.Parse(own)
+	if err != nil {
+		return err
+	}
+	if *rt <= 0 {
+		return fmt.Errorf("rt must be greater than 0")
+	}
+	h, err := newHandler(rest, time.Duration(*rt)*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           h,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	fmt.Fprintln(os.Stderr, "listening on", *addr)
+	return srv.ListenAndServe()
+}
+
+// splitFlags separates the flags called names (and their values)
+// from the rest of args, so subcommands can add their own flags on
+// top of the ones FromArgs understands. Every flag in names must
+// take a value.
+func splitFlags(args []string, names ...string) (own, rest []string) {
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		name, _, hasValue := strings.Cut(name, "=")
+		if !strings.HasPrefix(args[i], "-") || !contains(names, name) {
+			rest = append(rest, args[i])
+			continue
+		}
+		own = append(own, args[i])
+		if !hasValue && i+1 < len(args) {
+			i++
+			own = append(own, args[i])
+		}
+	}
+	return own, rest
+}
+
+// splitBools is splitFlags for boolean flags, which only take a
+// value when it's joined with =.
+func splitBools(args []string, names ...string) (own, rest []string) {
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && contains(names, name) {
+			own = append(own, arg)
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return own, rest
+}
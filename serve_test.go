@@ -0,0 +1,217 @@
+package search_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+// fakeEngines serves the saved result pages and api responses of
+// the engines below from testdata, and returns options pointing the
+// searcher at them. Any other engine name in
+// broken gets a 500.
+func fakeEngines(t *testing.T, broken ...string) (*httptest.Server, []search.Option) {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		data, err := os.ReadFile("testdata/" + name + ".html")
+		if errors.Is(err, os.ErrNotExist) {
+			// json apis
+			data, err = os.ReadFile("testdata/" + name + ".json")
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			// arxiv's atom feed
+			data, err = os.ReadFile("testdata/" + name + ".xml")
+		}
+		if err != nil {
+			http.Error(w, "no such engine", http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+	opts := []search.Option{
+		search.WithBaseURL("brave", ts.URL+"/brave?q="),
+		search.WithBaseURL("mojeek", ts.URL+"/mojeek?q="),
+		search.WithBaseURL("startpage", ts.URL+"/startpage?query="),
+		search.WithBaseURL("ecosia", ts.URL+"/ecosia?q="),
+		search.WithBaseURL("searxng", ts.URL+"/searxng?format=json&q="),
+		search.WithBaseURL("github", ts.URL+"/github?q="),
+		search.WithBaseURL("hn", ts.URL+"/hn?query="),
+		search.WithBaseURL("pkgsite", ts.URL+"/pkgsite?q="),
+		search.WithBaseURL("stackoverflow", ts.URL+"/stackoverflow?q="),
+		search.WithBaseURL("wikipedia", ts.URL+"/wikipedia?srsearch="),
+		search.WithBaseURL("wiktionary", ts.URL+"/wiktionary?srsearch="),
+		search.WithBaseURL("arxiv", ts.URL+"/arxiv?search_query=all:"),
+		search.WithBaseURL("crossref", ts.URL+"/crossref?query="),
+		search.WithBaseURL("semanticscholar", ts.URL+"/semanticscholar?query="),
+	}
+	for _, name := range broken {
+		opts = append(opts, search.WithBaseURL(name, ts.URL+"/broken-"+name+"?q="))
+	}
+	return ts, opts
+}
+
+type searchResponse struct {
+	Query   string   `json:"query"`
+	URLs    []string `json:"urls"`
+	Results []struct {
+		Engine string `json:"engine"`
+		Title  string `json:"title"`
+		URL    string `json:"url"`
+		Blurb  string `json:"blurb"`
+	} `json:"results"`
+}
+
+func TestServeSearch(t *testing.T) {
+	t.Parallel()
+	ts, opts := fakeEngines(t)
+	sv, err := search.NewServer([]string{"-engines", "brave,mojeek"}, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&term=cli", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	var resp searchResponse
+	err = json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Query != "golang" {
+		t.Errorf("got query %q want golang", resp.Query)
+	}
+	wantURLs := []string{
+		ts.URL + "/brave?q=golang+cli",
+		ts.URL + "/mojeek?q=golang+cli",
+	}
+	if strings.Join(resp.URLs, " ") != strings.Join(wantURLs, " ") {
+		t.Errorf("got urls %v want %v", resp.URLs, wantURLs)
+	}
+	var got []string
+	for _, r := range resp.Results {
+		got = append(got, r.Engine+" "+r.URL)
+	}
+	sort.Strings(got)
+	want := []string{
+		"brave https://github.com/spf13/cobra",
+		"brave https://go.dev/",
+		"mojeek https://go.dev/",
+		"mojeek https://pkg.go.dev/flag",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestServeSearchEscapes(t *testing.T) {
+	t.Parallel()
+	ts, opts := fakeEngines(t)
+	sv, err := search.NewServer([]string{"-engines", "brave"}, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{"a%26b", ts.URL + "/brave?q=a%26b"},
+		{"c%23", ts.URL + "/brave?q=c%23"},
+		// quotes around words still ask for exact matches
+		{"go%20%22a%3Db%22", ts.URL + `/brave?q=go+"a%3Db"`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q="+tt.q, nil))
+		var resp searchResponse
+		err = json.NewDecoder(rec.Body).Decode(&resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.URLs) != 1 || resp.URLs[0] != tt.want {
+			t.Errorf("q=%s: got urls %v want %s", tt.q, resp.URLs, tt.want)
+		}
+	}
+}
+
+func TestServeSearchText(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	sv, err := search.NewServer([]string{"-engines", "brave"}, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&format=text", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	want := "The Go Programming Language [brave]\nhttps://go.dev/\n"
+	if !strings.HasPrefix(rec.Body.String(), want) {
+		t.Errorf("got %q want prefix %q", rec.Body, want)
+	}
+}
+
+func TestServeSearchBadRequest(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t)
+	sv, err := search.NewServer(nil, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{
+		"/search",
+		"/search?q=golang&engines=altavista",
+		"/search?q=golang&format=xml",
+		"/search?q=golang&page=0",
+	} {
+		rec := httptest.NewRecorder()
+		sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d want %d", target, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	t.Parallel()
+	_, opts := fakeEngines(t, "duck")
+	sv, err := search.NewServer([]string{"-engines", "brave,duck"}, time.Second, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search?q=golang", nil))
+
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var resp struct {
+		Status  string `json:"status"`
+		Engines map[string]struct {
+			Status string `json:"status"`
+		} `json:"engines"`
+	}
+	err = json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"brave":  "ok",
+		"duck":   "failing",
+		"mojeek": "unknown",
+	}
+	for engine, status := range want {
+		if got := resp.Engines[engine].Status; got != status {
+			t.Errorf("%s: got status %q want %q", engine, got, status)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// parseTemplate parses the result template given with -format or,
+// from a file, with -template. A template may also define "header"
+// and "footer", which are written before and after each query's
+// results.
+func (s *searcher) parseTemplate(format, file string) (*template.Template, error) {
+	text := format
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		text = string(b)
+	} else if !strings.HasSuffix(text, "\n") {
+		// one result per line
+		text += "\n"
+	}
+	t, err := template.New("result").Funcs(template.FuncMap{
+		"domain":    domain,
+		"highlight": s.highlight,
+		"join":      strings.Join,
+		"truncate": func(n int, str string) string {
+			return truncate(str, n)
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return t, nil
+}
+
+// templateGroup is what a header or footer template is given.
+type templateGroup struct {
+	Query   string
+	Results []result
+}
+
+// templateWriter renders each result with a template. Without a
+// header or footer results are written as they arrive; otherwise
+// they're collected and written query by query.
+type templateWriter struct {
+	s       *searcher
+	tmpl    *template.Template
+	results []result
+}
+
+func (tw *templateWriter) grouped() bool {
+	return tw.tmpl.Lookup("header") != nil || tw.tmpl.Lookup("footer") != nil
+}
+
+func (tw *templateWriter) write(r result) error {
+	if tw.grouped() {
+		tw.results = append(tw.results, r)
+		return nil
+	}
+	return tw.tmpl.Execute(tw.s.output, r)
+}
+
+func (tw *templateWriter) close() error {
+	if !tw.grouped() {
+		return nil
+	}
+	queries, groups := tw.s.byQuery(tw.results)
+	for _, q := range queries {
+		g := templateGroup{Query: q, Results: groups[q]}
+		err := tw.section("header", g)
+		if err != nil {
+			return err
+		}
+		for _, r := range g.Results {
+			err = tw.tmpl.Execute(tw.s.output, r)
+			if err != nil {
+				return err
+			}
+		}
+		err = tw.section("footer", g)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// section executes the named template, if it's defined.
+func (tw *templateWriter) section(name string, g templateGroup) error {
+	if tw.tmpl.Lookup(name) == nil {
+		return nil
+	}
+	return tw.tmpl.ExecuteTemplate(tw.s.output, name, g)
+}
@@ -0,0 +1,91 @@
+package search_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davemolk/search"
+)
+
+var templateResults = []search.Result{
+	{Query: "golang cli", Engine: "brave", Rank: 1, Title: "The Go Programming Language", URL: "https://www.go.dev/learn", Blurb: "Build simple, secure, scalable systems with Go"},
+	{Query: "golang tui", Engine: "brave", Rank: 1, Title: "charmbracelet/bubbletea", URL: "https://github.com/charmbracelet/bubbletea", Blurb: "A powerful little TUI framework"},
+	{Query: "golang cli", Engine: "mojeek", Rank: 1, Title: "spf13/cobra", URL: "https://github.com/spf13/cobra", Blurb: "A Commander for modern Go CLI interactions"},
+}
+
+func writeTemplate(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	args = append([]string{"-s", "golang", "-engines", "brave,mojeek", "-color", "never"}, args...)
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs(append(args, "cli", "tui")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write(templateResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	out := writeTemplate(t, "-format", `{{.Rank}}. {{.Title}} <{{.URL}}> {{domain .URL}} {{.Blurb | truncate 11}}`)
+	want := `1. The Go Programming Language <https://www.go.dev/learn> go.dev Build simpl
+1. charmbracelet/bubbletea <https://github.com/charmbracelet/bubbletea> github.com A powerful 
+1. spf13/cobra <https://github.com/spf13/cobra> github.com A Commander
+`
+	if out != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestFormatHighlight(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&out),
+		search.FromArgs([]string{"-s", "golang", "-n", "-color", "always", "-format", "{{highlight .Title}}"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Write([]search.Result{{Title: "learn golang"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "learn " + s.Highlight("golang") + "\n"
+	if out.String() != want {
+		t.Fatalf("want %q, got %q", want, out.String())
+	}
+}
+
+func TestTemplateFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "results.tmpl")
+	tmpl := `{{define "header"}}== {{.Query}} ({{len .Results}})
+{{end}}{{define "footer"}}--
+{{end}}- {{.Title}} [{{.Engine}}]
+`
+	err := os.WriteFile(path, []byte(tmpl), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := writeTemplate(t, "-template", path)
+	want := `== golang cli (2)
+- The Go Programming Language [brave]
+- spf13/cobra [mojeek]
+--
+== golang tui (1)
+- charmbracelet/bubbletea [brave]
+--
+`
+	if out != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, out)
+	}
+}
@@ -0,0 +1,491 @@
+package search
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// terminal control sequences used by the interactive browser.
+const (
+	altScreenOn  = "\x1b[?1049h\x1b[?25l"
+	altScreenOff = "\x1b[?25h\x1b[?1049l"
+	clearScreen  = "\x1b[H\x1b[2J"
+)
+
+// previewLines is the number of rows given to the blurb preview.
+const previewLines = 6
+
+// browser holds the state of the interactive result browser.
+type browser struct {
+	query   string
+	results []result
+	cursor  int
+	offset  int
+	done    bool
+	filter  string
+	merged  bool
+	preview bool
+	status  string
+	typing  bool
+}
+
+// newBrowser returns a browser for s's query, starting out merged
+// with -merge.
+func (s *searcher) newBrowser() *browser {
+	return &browser{
+		merged: s.merged,
+		query:  strings.ReplaceAll(s.search, "+", " "),
+	}
+}
+
+// browserAction is what the caller of handle needs to do
+// after a key press.
+type browserAction int
+
+const (
+	actNone browserAction = iota
+	actQuit
+	actOpen
+	actCopy
+)
+
+// browse shows streamed results in an interactive terminal ui
+// until the user quits. It reads keys from and draws to the
+// controlling terminal, so search terms can still be piped in.
+func (s *searcher) browse(results <-chan result) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("unable to open terminal: %w", err)
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("unable to set up terminal: %w", err)
+	}
+	defer term.Restore(fd, state)
+	fmt.Fprint(tty, altScreenOn)
+	defer fmt.Fprint(tty, altScreenOff)
+
+	b := s.newBrowser()
+	keys := readKeys(tty)
+	for {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		b.render(tty, width, height)
+
+		select {
+		case r, ok := <-results:
+			if !ok {
+				results = nil
+				b.done = true
+				continue
+			}
+			b.add(r)
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch b.handle(k) {
+			case actQuit:
+				return nil
+			case actOpen:
+				if e, ok := b.selected(); ok {
+					if err := openURL(e.URL); err != nil {
+						b.status = err.Error()
+					} else {
+						b.status = "opened " + e.URL
+					}
+				}
+			case actCopy:
+				if e, ok := b.selected(); ok {
+					// OSC 52 asks the terminal to set the clipboard
+					fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(e.URL)))
+					b.status = "copied " + e.URL
+				}
+			}
+		}
+	}
+}
+
+// add appends a streamed result.
+func (b *browser) add(r result) {
+	b.results = append(b.results, r)
+}
+
+// entries returns the rows to display, grouped by engine or
+// merged by url, and narrowed by the current filter.
+func (b *browser) entries() []entry {
+	var all []entry
+	if b.merged {
+		all = merge(b.results)
+	} else {
+		for _, r := range b.results {
+			all = append(all, entry{result: r, Engines: []string{r.Engine}})
+		}
+		sort.SliceStable(all, func(i, j int) bool {
+			return all[i].Engine < all[j].Engine
+		})
+	}
+	if b.filter == "" {
+		return all
+	}
+	f := strings.ToLower(b.filter)
+	var filtered []entry
+	for _, e := range all {
+		text := strings.ToLower(strings.Join([]string{e.Title, e.URL, e.Blurb, strings.Join(e.Engines, " ")}, " "))
+		if strings.Contains(text, f) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// selected returns the entry under the cursor.
+func (b *browser) selected() (entry, bool) {
+	entries := b.entries()
+	if b.cursor < 0 || b.cursor >= len(entries) {
+		return entry{}, false
+	}
+	return entries[b.cursor], true
+}
+
+// handle updates the browser state for key k.
+func (b *browser) handle(k string) browserAction {
+	b.status = ""
+	if k == "ctrl-c" {
+		return actQuit
+	}
+	if b.typing {
+		switch k {
+		case "enter":
+			b.typing = false
+		case "esc":
+			b.typing = false
+			b.filter = ""
+		case "backspace":
+			if len(b.filter) > 0 {
+				_, size := utf8.DecodeLastRuneInString(b.filter)
+				b.filter = b.filter[:len(b.filter)-size]
+			}
+		default:
+			if utf8.RuneCountInString(k) == 1 {
+				b.filter += k
+			}
+		}
+		b.cursor = 0
+		return actNone
+	}
+	switch k {
+	case "q":
+		return actQuit
+	case "j", "down":
+		b.cursor++
+	case "k", "up":
+		b.cursor--
+	case "pgdn":
+		b.cursor += 10
+	case "pgup":
+		b.cursor -= 10
+	case "g":
+		b.cursor = 0
+	case "G":
+		b.cursor = len(b.entries()) - 1
+	case "/":
+		b.typing = true
+	case "esc":
+		b.filter = ""
+	case "enter", "p":
+		b.preview = !b.preview
+	case "m":
+		b.merged = !b.merged
+		b.cursor = 0
+	case "o":
+		return actOpen
+	case "y":
+		return actCopy
+	}
+	if n := len(b.entries()); b.cursor >= n {
+		b.cursor = n - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	return actNone
+}
+
+// render draws the browser to w, which is width by height cells.
+func (b *browser) render(w io.Writer, width, height int) {
+	entries := b.entries()
+	if b.cursor >= len(entries) {
+		b.cursor = len(entries) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+
+	var lines []string
+	mode := "grouped"
+	if b.merged {
+		mode = "merged"
+	}
+	state := "searching..."
+	if b.done {
+		state = "done"
+	}
+	lines = append(lines, truncateCells(fmt.Sprintf("search: %s | %d of %d results | %s | %s", sanitize(b.query), len(entries), len(b.results), mode, state), width))
+
+	rows := height - 2
+	if b.preview {
+		rows -= previewLines + 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+	for i := b.offset; i < b.offset+rows; i++ {
+		if i >= len(entries) {
+			lines = append(lines, "")
+			continue
+		}
+		e := entries[i]
+		title := sanitize(e.Title)
+		if title == "" {
+			title = sanitize(e.URL)
+		}
+		marker := "  "
+		if i == b.cursor {
+			marker = "> "
+		}
+		lines = append(lines, truncateCells(fmt.Sprintf("%s%-8s %s", marker, strings.Join(e.Engines, ","), title), width))
+	}
+
+	if b.preview {
+		lines = append(lines, strings.Repeat("-", width))
+		var preview []string
+		if e, ok := b.selected(); ok {
+			preview = append(preview, truncateCells(sanitize(e.URL), width))
+			preview = append(preview, wrap(sanitize(e.Blurb), width)...)
+		}
+		for i := 0; i < previewLines; i++ {
+			if i < len(preview) {
+				lines = append(lines, preview[i])
+			} else {
+				lines = append(lines, "")
+			}
+		}
+	}
+
+	footer := "j/k move  / filter  enter preview  m merge  o open  y copy  q quit"
+	switch {
+	case b.typing:
+		footer = "/" + sanitize(b.filter) + "_"
+	case b.status != "":
+		footer = sanitize(b.status)
+	case b.filter != "":
+		footer = "filter: " + sanitize(b.filter) + "  (esc to clear)"
+	}
+	lines = append(lines, truncateCells(footer, width))
+
+	fmt.Fprint(w, clearScreen+strings.Join(lines, "\r\n"))
+}
+
+// readKeys decodes key presses from r and sends them on the
+// returned channel, which is closed when r returns an error.
+func readKeys(r io.Reader) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		buf := make([]byte, 32)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, k := range decodeKeys(buf[:n]) {
+				out <- k
+			}
+		}
+	}()
+	return out
+}
+
+// decodeKeys turns raw terminal input into key names.
+func decodeKeys(b []byte) []string {
+	switch string(b) {
+	case "\x1b[A", "\x1bOA":
+		return []string{"up"}
+	case "\x1b[B", "\x1bOB":
+		return []string{"down"}
+	case "\x1b[5~":
+		return []string{"pgup"}
+	case "\x1b[6~":
+		return []string{"pgdn"}
+	}
+	var keys []string
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		switch r {
+		case 3:
+			keys = append(keys, "ctrl-c")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 8, 127:
+			keys = append(keys, "backspace")
+		case 27:
+			keys = append(keys, "esc")
+			// ignore the rest of an unknown escape sequence
+			return keys
+		default:
+			if r >= ' ' {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys
+}
+
+// openURL opens u in the default browser.
+func openURL(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to open %s: %w", u, err)
+	}
+	return cmd.Process.Release()
+}
+
+// sanitize replaces whitespace control characters in str with
+// spaces and drops the rest, so scraped text can't move the
+// cursor or inject escape sequences into the terminal.
+func sanitize(str string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, str)
+}
+
+// wide lists the ranges of east asian wide and fullwidth runes,
+// which take up two terminal cells.
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe4f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// cells returns how many terminal cells r takes up.
+func cells(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.Is(wide, r):
+		return 2
+	}
+	return 1
+}
+
+// truncate shortens str to at most n runes.
+func truncate(str string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(str) <= n {
+		return str
+	}
+	return string([]rune(str)[:n])
+}
+
+// truncateCells shortens str to at most n terminal cells.
+func truncateCells(str string, n int) string {
+	width := 0
+	for i, r := range str {
+		width += cells(r)
+		if width > n {
+			return str[:i]
+		}
+	}
+	return str
+}
+
+// wrap breaks str into lines of at most width terminal cells,
+// splitting on spaces where possible.
+func wrap(str string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	var lines []string
+	var line []rune
+	used := 0
+	for _, word := range strings.Fields(str) {
+		w := 0
+		for _, r := range word {
+			w += cells(r)
+		}
+		if len(line) > 0 && used+1+w > width {
+			lines = append(lines, string(line))
+			line, used = nil, 0
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+			used++
+		}
+		for _, r := range word {
+			if c := cells(r); used+c > width && len(line) > 0 {
+				lines = append(lines, string(line))
+				line, used = nil, 0
+			}
+			line = append(line, r)
+			used += cells(r)
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
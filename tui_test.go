@@ -0,0 +1,192 @@
+package search_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/davemolk/search"
+)
+
+func newTestBrowser() *search.Browser {
+	b := &search.Browser{}
+	b.Add(search.Result{Engine: "mojeek", Title: "Go", URL: "https://go.dev", Blurb: "the go programming language"})
+	b.Add(search.Result{Engine: "brave", Title: "Gophers", URL: "https://gophers.dev", Blurb: "a gopher community"})
+	b.Add(search.Result{Engine: "brave", Title: "Go", URL: "https://go.dev", Blurb: "the go programming language"})
+	return b
+}
+
+func TestBrowserGroupedByEngine(t *testing.T) {
+	t.Parallel()
+	b := newTestBrowser()
+	want := []string{
+		"brave https://gophers.dev",
+		"brave https://go.dev",
+		"mojeek https://go.dev",
+	}
+	if got := b.Rows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestBrowserMerged(t *testing.T) {
+	t.Parallel()
+	b := newTestBrowser()
+	b.Key("m")
+	want := []string{
+		"mojeek,brave https://go.dev",
+		"brave https://gophers.dev",
+	}
+	if got := b.Rows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestBrowserMergeFlag(t *testing.T) {
+	t.Parallel()
+	for _, merge := range []bool{false, true} {
+		args := []string{"-s", "go", "-n", "-i"}
+		if merge {
+			args = append(args, "-merge")
+		}
+		s, err := search.NewSearcher(search.FromArgs(args))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := s.NewBrowser()
+		b.Add(search.Result{Engine: "mojeek", URL: "https://go.dev"})
+		b.Add(search.Result{Engine: "brave", URL: "https://go.dev"})
+		want := 2
+		if merge {
+			want = 1
+		}
+		if got := len(b.Rows()); got != want {
+			t.Errorf("merge %t: want %d rows, got %v", merge, want, b.Rows())
+		}
+	}
+}
+
+func TestBrowserFilter(t *testing.T) {
+	t.Parallel()
+	b := newTestBrowser()
+	for _, k := range []string{"/", "C", "o", "m", "enter"} {
+		b.Key(k)
+	}
+	want := []string{"brave https://gophers.dev"}
+	if got := b.Rows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+	b.Key("esc")
+	if got := len(b.Rows()); got != 3 {
+		t.Errorf("got %d rows after clearing filter, want 3", got)
+	}
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	t.Parallel()
+	b := newTestBrowser()
+	b.Key("j")
+	b.Key("down")
+	b.Key("j")
+	if got, want := b.Selected(), "https://go.dev"; got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+	b.Key("g")
+	if got, want := b.Selected(), "https://gophers.dev"; got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	t.Parallel()
+	tests := map[string][]string{
+		"\x1b[A": {"up"},
+		"\x1b[B": {"down"},
+		"jk":     {"j", "k"},
+		"\r":     {"enter"},
+		"\x7f":   {"backspace"},
+		"\x03":   {"ctrl-c"},
+		"é":      {"é"},
+	}
+	for in, want := range tests {
+		if got := search.DecodeKeys([]byte(in)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %v want %v", in, got, want)
+		}
+	}
+}
+
+func TestBrowserRenderStripsControl(t *testing.T) {
+	t.Parallel()
+	b := &search.Browser{}
+	b.Add(search.Result{Engine: "brave", Title: "Go\x1b[2J\x1b]0;pwned\x07", URL: "https://go.dev", Blurb: "the go\r\nprogramming language"})
+	b.Key("enter")
+	screen := b.Render(40, 12)
+	lines := strings.Split(strings.TrimPrefix(screen, "\x1b[H\x1b[2J"), "\r\n")
+	for _, l := range lines {
+		if strings.IndexFunc(l, unicode.IsControl) >= 0 {
+			t.Errorf("got control characters in %q", l)
+		}
+	}
+	if !strings.Contains(screen, "the go programming language") {
+		t.Errorf("blurb missing from %q", screen)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"golang", 4, "gola"},
+		{"日本語のテキスト", 5, "日本語のテ"},
+		{"e\u0301te\u0301", 3, "e\u0301t"},
+		{"golang", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := search.Truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("Truncate(%q, %d): got %q want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateCells(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"golang", 4, "gola"},
+		{"golang", 10, "golang"},
+		{"日本語のテキスト", 5, "日本"},
+		{"日本語", 6, "日本語"},
+		{"été", 3, "été"},
+		{"golang", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := search.TruncateCells(tt.in, tt.n); got != tt.want {
+			t.Errorf("TruncateCells(%q, %d): got %q want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in    string
+		width int
+		want  []string
+	}{
+		{"the go programming language", 10, []string{"the go", "programmin", "g language"}},
+		{"日本語のテキスト", 6, []string{"日本語", "のテキ", "スト"}},
+		{"go 日本語", 5, []string{"go", "日本", "語"}},
+	}
+	for _, tt := range tests {
+		if got := search.Wrap(tt.in, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Wrap(%q, %d): got %q want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,32 @@
 package search
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
 
 var (
-	ErrNoSearchTerm = errors.New("must provide search term(s)")
-	ErrInvalidOS    = errors.New("os must be l, m, or w")
+	ErrNoSearchTerm    = errors.New("must provide search term(s)")
+	ErrInvalidOS       = errors.New("os must be l, m, or w")
+	ErrInvalidColor    = errors.New("color must be auto, always, or never")
+	ErrInvalidPage     = errors.New("page must be at least 1")
+	ErrInvalidFetch    = errors.New("fetch must be 0 or more")
+	ErrInvalidEngine   = errors.New("engines must be arxiv, bing, brave, crossref, duck, ecosia, github, githubcode, hn, mojeek, pkgsite, qwant, searxng, semanticscholar, stackoverflow, startpage, wikipedia, wiktionary, or yahoo")
+	ErrInvalidAPIKey   = errors.New("api keys are only used by bing, brave, and github")
+	ErrInvalidGroup    = errors.New("group must be academic, dev, or reference")
+	ErrInvalidBoost    = errors.New("boost must be domain=factor, with factor 0 or more")
+	ErrInvalidExec     = errors.New("exec must be a command")
+	ErrInvalidWebhook  = errors.New("webhook must be an http or https url")
+	ErrInvalidFormat   = errors.New("o must be text, csv, tsv, markdown, html, json, or bibtex")
+	ErrInvalidSearXNG  = errors.New("searxng must be the http or https url of a SearXNG instance")
+	ErrInvalidTemplate = errors.New("invalid template")
+	ErrInvalidColumn   = errors.New("columns must be query, engine, rank, title, url, blurb, date, source, image, thumbnail, width, height, duration, authors, year, doi, venue, or pdf")
+	ErrInvalidRegion   = errors.New("region must be a two letter country code")
+	ErrInvalidLang     = errors.New("lang must be a two letter language code")
+	ErrInvalidSafe     = errors.New("safe must be off, moderate, or strict")
+	ErrInvalidSince    = errors.New("since must be day, week, month, or year")
+	ErrInvalidType     = errors.New("type must be web, news, images, or videos")
 )
 
 func (s *searcher) validateTerms(str string) error {
@@ -22,3 +44,136 @@ func (s *searcher) validateOS(str string) error {
 		return ErrInvalidOS
 	}
 }
+
+func (s *searcher) validateColor(str string) error {
+	switch str {
+	case "auto", "always", "never":
+		return nil
+	default:
+		return ErrInvalidColor
+	}
+}
+
+func (s *searcher) validatePage(n int) error {
+	if n < 1 {
+		return ErrInvalidPage
+	}
+	return nil
+}
+
+func (s *searcher) validateFormat(str string) error {
+	switch str {
+	case "text", "csv", "tsv", "markdown", "html", "json", "bibtex":
+		return nil
+	default:
+		return ErrInvalidFormat
+	}
+}
+
+func (s *searcher) validateColumns(names ...string) error {
+	for _, name := range names {
+		if !contains(csvColumns, name) && !contains(verticalColumns, name) && !contains(paperColumns, name) {
+			return fmt.Errorf("%w: got %q", ErrInvalidColumn, name)
+		}
+	}
+	return nil
+}
+
+func (s *searcher) validateFetch(n int) error {
+	if n < 0 {
+		return ErrInvalidFetch
+	}
+	return nil
+}
+
+func (s *searcher) validateEngines(names ...string) error {
+	for _, name := range names {
+		if !contains(engineNames, name) {
+			return fmt.Errorf("%w: got %q", ErrInvalidEngine, name)
+		}
+	}
+	return nil
+}
+
+func (s *searcher) validateGroup(group string) error {
+	if _, ok := engineGroups[group]; !ok {
+		return fmt.Errorf("%w: got %q", ErrInvalidGroup, group)
+	}
+	return nil
+}
+
+func (s *searcher) validateWebhook(str string) error {
+	if str == "" {
+		return nil
+	}
+	u, err := url.Parse(str)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: got %q", ErrInvalidWebhook, str)
+	}
+	return nil
+}
+
+// validateSearXNG checks the instance url, which -engines searxng
+// can't do without.
+func (s *searcher) validateSearXNG(str string) error {
+	if str == "" {
+		if contains(s.engineNames, "searxng") && s.searxngURL == "" && s.bases["searxng"] == "" {
+			return fmt.Errorf("%w: set -searxng or $SEARXNG_URL", ErrInvalidSearXNG)
+		}
+		return nil
+	}
+	u, err := url.Parse(str)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: got %q", ErrInvalidSearXNG, str)
+	}
+	return nil
+}
+
+func (s *searcher) validateType(vertical string) error {
+	if !contains(verticals, vertical) {
+		return fmt.Errorf("%w: got %q", ErrInvalidType, vertical)
+	}
+	return nil
+}
+
+func (s *searcher) validateSince(since string) error {
+	if since != "" && !contains(sinceRanges, since) {
+		return fmt.Errorf("%w: got %q", ErrInvalidSince, since)
+	}
+	return nil
+}
+
+func (s *searcher) validateLocale(l locale) error {
+	if l.region != "" && !isLetters(l.region, 2) {
+		return fmt.Errorf("%w: got %q", ErrInvalidRegion, l.region)
+	}
+	if l.lang != "" && !isLetters(l.lang, 2) {
+		return fmt.Errorf("%w: got %q", ErrInvalidLang, l.lang)
+	}
+	if l.safe != "" && !contains(safeLevels, l.safe) {
+		return fmt.Errorf("%w: got %q", ErrInvalidSafe, l.safe)
+	}
+	return nil
+}
+
+// isLetters reports whether str is n lowercase letters.
+func isLetters(str string, n int) bool {
+	if len(str) != n {
+		return false
+	}
+	for _, r := range str {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, str string) bool {
+	for _, l := range list {
+		if l == str {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package search_test
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/davemolk/search"
@@ -28,3 +29,218 @@ func TestInvalidOS(t *testing.T) {
 		t.Fatal("did not fail with ErrInvalidOS")
 	}
 }
+
+func TestInvalidColor(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-color", "sometimes"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidColor) {
+		t.Fatal("did not fail with ErrInvalidColor")
+	}
+}
+
+func TestInvalidPage(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-page", "0"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidPage) {
+		t.Fatal("did not fail with ErrInvalidPage")
+	}
+}
+
+func TestInvalidEngine(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-engines", "brave,altavista"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidEngine) {
+		t.Fatal("did not fail with ErrInvalidEngine")
+	}
+}
+
+func TestInvalidFetch(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-fetch", "-1"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidFetch) {
+		t.Fatal("did not fail with ErrInvalidFetch")
+	}
+}
+
+func TestInvalidBoost(t *testing.T) {
+	t.Parallel()
+	for _, boost := range []string{"go.dev", "go.dev=x", "=2", "go.dev=-1"} {
+		args := []string{"-s", "foo", "-merge", "-boost", boost}
+		_, err := search.NewSearcher(
+			search.FromArgs(args),
+		)
+		if !errors.Is(err, search.ErrInvalidBoost) {
+			t.Fatalf("%q did not fail with ErrInvalidBoost", boost)
+		}
+	}
+}
+
+func TestBoostWithoutMerge(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-boost", "go.dev=2"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidBoost) {
+		t.Fatal("did not fail with ErrInvalidBoost")
+	}
+}
+
+func TestInvalidWebhook(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-webhook", "ftp://example.com"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidWebhook) {
+		t.Fatal("did not fail with ErrInvalidWebhook")
+	}
+}
+
+func TestInvalidAPIKey(t *testing.T) {
+	t.Parallel()
+	_, err := search.NewSearcher(
+		search.WithAPIKey("duck", "secret"),
+	)
+	if !errors.Is(err, search.ErrInvalidAPIKey) {
+		t.Fatal("did not fail with ErrInvalidAPIKey")
+	}
+}
+
+func TestInvalidRegion(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-region", "usa"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidRegion) {
+		t.Fatal("did not fail with ErrInvalidRegion")
+	}
+}
+
+func TestInvalidLang(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-lang", "e1"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidLang) {
+		t.Fatal("did not fail with ErrInvalidLang")
+	}
+}
+
+func TestInvalidSafe(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-safe", "high"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidSafe) {
+		t.Fatal("did not fail with ErrInvalidSafe")
+	}
+}
+
+func TestInvalidGroup(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-group", "science"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidGroup) {
+		t.Fatal("did not fail with ErrInvalidGroup")
+	}
+}
+
+func TestInvalidSince(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-since", "decade"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidSince) {
+		t.Fatal("did not fail with ErrInvalidSince")
+	}
+}
+
+func TestInvalidType(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-type", "maps"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidType) {
+		t.Fatal("did not fail with ErrInvalidType")
+	}
+}
+
+func TestInvalidSearXNG(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-engines", "searxng", "-searxng", "searx.example.org"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidSearXNG) {
+		t.Fatal("did not fail with ErrInvalidSearXNG")
+	}
+}
+
+func TestInvalidExec(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-exec", `echo "{url}`}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidExec) {
+		t.Fatal("did not fail with ErrInvalidExec")
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-o", "xml"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidFormat) {
+		t.Fatal("did not fail with ErrInvalidFormat")
+	}
+}
+
+func TestInvalidColumn(t *testing.T) {
+	t.Parallel()
+	args := []string{"-s", "foo", "-o", "csv", "-columns", "url,score"}
+	_, err := search.NewSearcher(
+		search.FromArgs(args),
+	)
+	if !errors.Is(err, search.ErrInvalidColumn) {
+		t.Fatal("did not fail with ErrInvalidColumn")
+	}
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	t.Parallel()
+	for _, args := range [][]string{
+		{"-format", "{{.Title"},
+		{"-template", filepath.Join(t.TempDir(), "missing.tmpl")},
+		{"-format", "{{.Title}}", "-o", "csv"},
+	} {
+		_, err := search.NewSearcher(
+			search.FromArgs(append([]string{"-s", "foo", "-n"}, args...)),
+		)
+		if !errors.Is(err, search.ErrInvalidTemplate) {
+			t.Errorf("%q did not fail with ErrInvalidTemplate: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,540 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// verticals are the kinds of results -type can ask for.
+var verticals = []string{"web", "news", "images", "videos"}
+
+// verticalQueries swaps each engine for its news, images, or videos
+// search. Engines without one are left with nothing to search with,
+// and skipped.
+func (s *searcher) verticalQueries() {
+	if s.vertical == "" || s.vertical == "web" {
+		return
+	}
+	queries := s.queriesFor(s.vertical)
+	for _, e := range s.allEngines() {
+		if q, ok := queries[e.name]; ok {
+			*e = *q
+			continue
+		}
+		*e = query{name: e.name}
+	}
+}
+
+// queriesFor returns the news, images, or videos queries by engine
+// name, or nil for web.
+func (s *searcher) queriesFor(vertical string) map[string]*query {
+	switch vertical {
+	case "news":
+		return s.newsQueries()
+	case "images":
+		return s.imageQueries()
+	case "videos":
+		return s.videoQueries()
+	}
+	return nil
+}
+
+// searchable reports whether q has selectors or a decoder to read
+// results with.
+func (q *query) searchable() bool {
+	return q.itemSelector != "" || q.decode != nil
+}
+
+// skipped returns the engines that would have been searched, but
+// can't search -type.
+func (s *searcher) skipped() []string {
+	var names []string
+	for _, e := range s.requested() {
+		if e.base == "" {
+			names = append(names, e.name)
+		}
+	}
+	return names
+}
+
+func (s *searcher) newsQueries() map[string]*query {
+	queries := map[string]*query{
+		"bing": {
+			base:          "https://www.bing.com/news/search?q=",
+			blurbSelector: "div.snippet",
+			extract: func(g *goquery.Selection, r *result) {
+				r.Source, _ = g.Attr("data-author")
+				r.Date, _ = g.Find("div.source span[aria-label]").Attr("aria-label")
+			},
+			itemSelector:  "div.news-card",
+			linkSelector:  "a.title",
+			localize:      bingLocale,
+			name:          "bing",
+			pageParam:     "first",
+			pageSize:      10,
+			pageStart:     1,
+			since:         sinceParams("qft", "interval%3d%227%22", "interval%3d%228%22", "interval%3d%229%22", ""),
+			titleSelector: "a.title",
+		},
+		"brave": {
+			base:          "https://search.brave.com/news?q=",
+			blurbSelector: "p.snippet-description",
+			extract: func(g *goquery.Selection, r *result) {
+				r.Source = strings.TrimSpace(g.Find("cite.snippet-url span.netloc").Text())
+				r.Date = strings.TrimSpace(g.Find("span.snippet-age").Text())
+			},
+			itemSelector:  "div.snippet[data-type='news']",
+			linkSelector:  "a.result-header",
+			localize:      braveLocale,
+			name:          "brave",
+			pageParam:     "offset",
+			pageSize:      1,
+			pageStart:     0,
+			since:         sinceParams("tf", "pd", "pw", "pm", "py"),
+			titleSelector: "span.snippet-title",
+		},
+		"mojeek": {
+			base:          "https://www.mojeek.com/search?fmt=news&q=",
+			blurbSelector: "li > p.s",
+			extract: func(g *goquery.Selection, r *result) {
+				r.Source = strings.TrimSpace(g.Find("p.i > span.src").Text())
+				r.Date = strings.TrimSpace(g.Find("p.i > span.date").Text())
+			},
+			itemSelector:  "ul.results-standard > li",
+			linkSelector:  "li > a.ob",
+			localize:      mojeekLocale,
+			name:          "mojeek",
+			pageParam:     "s",
+			pageSize:      10,
+			pageStart:     1,
+			titleSelector: "li > h2 > a",
+		},
+		"yahoo": {
+			base:          "https://news.search.yahoo.com/search?p=",
+			blurbSelector: "p.s-desc",
+			extract: func(g *goquery.Selection, r *result) {
+				r.Source = strings.TrimSpace(g.Find("span.s-source").Text())
+				r.Date = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(g.Find("span.s-time").Text()), "·"))
+			},
+			itemSelector:  "div.NewsArticle",
+			linkSelector:  "h4.s-title > a",
+			localize:      yahooLocale,
+			name:          "yahoo",
+			pageParam:     "b",
+			pageSize:      10,
+			pageStart:     1,
+			since:         sinceParams("btf", "d", "w", "m", ""),
+			titleSelector: "h4.s-title > a",
+		},
+		"searxng": s.searxngVertical("news"),
+	}
+	if key := s.apiKeys["brave"]; key != "" {
+		queries["brave"] = braveAPI(key, "news", decodeBraveNews)
+	}
+	if key := s.apiKeys["bing"]; key != "" {
+		queries["bing"] = bingAPI(key, "news/", decodeBingNews)
+	}
+	return queries
+}
+
+func (s *searcher) imageQueries() map[string]*query {
+	queries := map[string]*query{
+		"bing": {
+			base: "https://www.bing.com/images/search?q=",
+			// the result is in the link's m attribute, as json
+			extract: func(g *goquery.Selection, r *result) {
+				var m struct {
+					PageURL  string `json:"purl"`
+					ImageURL string `json:"murl"`
+					ThumbURL string `json:"turl"`
+					Title    string `json:"t"`
+					Desc     string `json:"desc"`
+				}
+				data, _ := g.Find("a.iusc").Attr("m")
+				if json.Unmarshal([]byte(data), &m) != nil {
+					return
+				}
+				r.URL = m.PageURL
+				r.Title = m.Title
+				r.Blurb = m.Desc
+				r.Image = m.ImageURL
+				r.Thumbnail = m.ThumbURL
+				r.Width, r.Height = dimensions(g.Find("div.img_info span.nowrap").Text())
+			},
+			itemSelector: "div.imgpt",
+			localize:     bingLocale,
+			name:         "bing",
+			pageParam:    "first",
+			pageSize:     35,
+			pageStart:    1,
+			since:        ageFilter,
+		},
+		"searxng": s.searxngVertical("images"),
+	}
+	if key := s.apiKeys["brave"]; key != "" {
+		queries["brave"] = braveAPI(key, "images", decodeBraveImages)
+	}
+	if key := s.apiKeys["bing"]; key != "" {
+		queries["bing"] = bingAPI(key, "images/", decodeBingImages)
+	}
+	return queries
+}
+
+func (s *searcher) videoQueries() map[string]*query {
+	queries := map[string]*query{
+		"bing": {
+			base: "https://www.bing.com/videos/search?q=",
+			// the result is in the vrhm attribute, as json
+			extract: func(g *goquery.Selection, r *result) {
+				var m struct {
+					PageURL  string `json:"pgurl"`
+					Title    string `json:"vt"`
+					Duration string `json:"du"`
+				}
+				data, _ := g.Find("div.vrhdata").Attr("vrhm")
+				if json.Unmarshal([]byte(data), &m) != nil {
+					return
+				}
+				r.URL = m.PageURL
+				r.Title = m.Title
+				r.Duration = m.Duration
+				r.Source = strings.TrimSpace(g.Find("div.mc_vtvc_meta_row span.mc_vtvc_meta_row_channel").Text())
+				r.Thumbnail, _ = g.Find("img").Attr("data-src-hq")
+			},
+			itemSelector: "div.mc_vtvc",
+			localize:     bingLocale,
+			name:         "bing",
+			pageParam:    "first",
+			pageSize:     35,
+			pageStart:    1,
+			since:        ageFilter,
+		},
+		"searxng": s.searxngVertical("videos"),
+	}
+	if key := s.apiKeys["brave"]; key != "" {
+		queries["brave"] = braveAPI(key, "videos", decodeBraveVideos)
+	}
+	if key := s.apiKeys["bing"]; key != "" {
+		queries["bing"] = bingAPI(key, "videos/", decodeBingVideos)
+	}
+	return queries
+}
+
+// searxngVertical searches a SearXNG category.
+func (s *searcher) searxngVertical(category string) *query {
+	q := *s.searxng
+	q.base = strings.Replace(q.base, "format=json", "format=json&categories="+category, 1)
+	return &q
+}
+
+// ageFilter limits bing images and videos to the past day, week,
+// month, or year, in minutes.
+var ageFilter = sinceParams("qft", "%2bfilterui%3aage-lt1440", "%2bfilterui%3aage-lt10080", "%2bfilterui%3aage-lt43200", "%2bfilterui%3aage-lt525600")
+
+var dimensionsRe = regexp.MustCompile(`(\d+)\s*[x×]\s*(\d+)`)
+
+// dimensions reads an image's size from text like "1920 x 1080 · jpeg".
+func dimensions(str string) (int, int) {
+	m := dimensionsRe.FindStringSubmatch(str)
+	if m == nil {
+		return 0, 0
+	}
+	w, _ := strconv.Atoi(m[1])
+	h, _ := strconv.Atoi(m[2])
+	return w, h
+}
+
+var isoDurationRe = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// clockDuration turns an ISO 8601 duration, like PT1H3M21S, into
+// 1:03:21. Anything else is returned as it is.
+func clockDuration(str string) string {
+	m := isoDurationRe.FindStringSubmatch(str)
+	if m == nil {
+		return str
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	sec, _ := strconv.Atoi(m[3])
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, min, sec)
+	}
+	return fmt.Sprintf("%d:%02d", min, sec)
+}
+
+// details summarizes the fields only news, images, videos, and
+// papers have, e.g. "The Verge · 2 hours ago" or "1920x1080".
+func (r result) details() string {
+	var parts []string
+	for _, p := range []string{r.citation(), r.Venue, r.Source, r.Date, r.Duration} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if r.Width > 0 && r.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", r.Width, r.Height))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// braveAPI is a Brave Search API vertical: news, images, or videos.
+func braveAPI(key, vertical string, decode func(io.Reader) ([]result, error)) *query {
+	q := &query{
+		base:   "https://api.search.brave.com/res/v1/" + vertical + "/search?q=",
+		decode: decode,
+		header: map[string]string{
+			"Accept":               "application/json",
+			"X-Subscription-Token": key,
+		},
+		localize:  braveAPILocale,
+		name:      "brave",
+		pageParam: "offset",
+		pageSize:  1,
+		pageStart: 0,
+		verify:    true,
+	}
+	if vertical == "images" {
+		// image search isn't paginated or limited by age
+		q.pageParam = ""
+	} else {
+		q.since = sinceParams("freshness", "pd", "pw", "pm", "py")
+	}
+	return q
+}
+
+// bingAPI is a Bing Web Search API vertical. path is "news/",
+// "images/", or "videos/".
+func bingAPI(key, path string, decode func(io.Reader) ([]result, error)) *query {
+	return &query{
+		base:   "https://api.bing.microsoft.com/v7.0/" + path + "search?q=",
+		decode: decode,
+		header: map[string]string{
+			"Accept":                    "application/json",
+			"Ocp-Apim-Subscription-Key": key,
+		},
+		localize:  bingAPILocale,
+		name:      "bing",
+		pageParam: "offset",
+		pageSize:  10,
+		pageStart: 0,
+		since:     sinceParams("freshness", "Day", "Week", "Month", ""),
+		verify:    true,
+	}
+}
+
+type braveThumbnail struct {
+	Src string `json:"src"`
+}
+
+func decodeBraveNews(body io.Reader) ([]result, error) {
+	var resp struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			Age         string `json:"age"`
+			MetaURL     struct {
+				Hostname string `json:"hostname"`
+			} `json:"meta_url"`
+			Thumbnail braveThumbnail `json:"thumbnail"`
+		} `json:"results"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Results {
+		results = append(results, result{
+			Title:     stripTags(r.Title),
+			URL:       r.URL,
+			Blurb:     stripTags(r.Description),
+			Date:      r.Age,
+			Source:    r.MetaURL.Hostname,
+			Thumbnail: r.Thumbnail.Src,
+		})
+	}
+	return results, nil
+}
+
+func decodeBraveImages(body io.Reader) ([]result, error) {
+	var resp struct {
+		Results []struct {
+			Title      string         `json:"title"`
+			URL        string         `json:"url"`
+			Source     string         `json:"source"`
+			Thumbnail  braveThumbnail `json:"thumbnail"`
+			Properties struct {
+				URL    string `json:"url"`
+				Width  int    `json:"width"`
+				Height int    `json:"height"`
+			} `json:"properties"`
+		} `json:"results"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Results {
+		results = append(results, result{
+			Title:     stripTags(r.Title),
+			URL:       r.URL,
+			Source:    r.Source,
+			Image:     r.Properties.URL,
+			Thumbnail: r.Thumbnail.Src,
+			Width:     r.Properties.Width,
+			Height:    r.Properties.Height,
+		})
+	}
+	return results, nil
+}
+
+func decodeBraveVideos(body io.Reader) ([]result, error) {
+	var resp struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			Age         string `json:"age"`
+			Video       struct {
+				Duration  string `json:"duration"`
+				Creator   string `json:"creator"`
+				Publisher string `json:"publisher"`
+			} `json:"video"`
+			Thumbnail braveThumbnail `json:"thumbnail"`
+		} `json:"results"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Results {
+		source := r.Video.Creator
+		if source == "" {
+			source = r.Video.Publisher
+		}
+		results = append(results, result{
+			Title:     stripTags(r.Title),
+			URL:       r.URL,
+			Blurb:     stripTags(r.Description),
+			Date:      r.Age,
+			Duration:  r.Video.Duration,
+			Source:    source,
+			Thumbnail: r.Thumbnail.Src,
+		})
+	}
+	return results, nil
+}
+
+type bingThumbnail struct {
+	ContentURL string `json:"contentUrl"`
+}
+
+type bingProvider struct {
+	Name string `json:"name"`
+}
+
+func decodeBingNews(body io.Reader) ([]result, error) {
+	var resp struct {
+		Value []struct {
+			Name          string         `json:"name"`
+			URL           string         `json:"url"`
+			Description   string         `json:"description"`
+			DatePublished string         `json:"datePublished"`
+			Provider      []bingProvider `json:"provider"`
+			Image         struct {
+				Thumbnail bingThumbnail `json:"thumbnail"`
+			} `json:"image"`
+		} `json:"value"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Value {
+		res := result{
+			Title:     r.Name,
+			URL:       r.URL,
+			Blurb:     r.Description,
+			Date:      r.DatePublished,
+			Thumbnail: r.Image.Thumbnail.ContentURL,
+		}
+		if len(r.Provider) > 0 {
+			res.Source = r.Provider[0].Name
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func decodeBingImages(body io.Reader) ([]result, error) {
+	var resp struct {
+		Value []struct {
+			Name         string `json:"name"`
+			HostPageURL  string `json:"hostPageUrl"`
+			ContentURL   string `json:"contentUrl"`
+			ThumbnailURL string `json:"thumbnailUrl"`
+			Width        int    `json:"width"`
+			Height       int    `json:"height"`
+		} `json:"value"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Value {
+		results = append(results, result{
+			Title:     r.Name,
+			URL:       r.HostPageURL,
+			Image:     r.ContentURL,
+			Thumbnail: r.ThumbnailURL,
+			Width:     r.Width,
+			Height:    r.Height,
+		})
+	}
+	return results, nil
+}
+
+func decodeBingVideos(body io.Reader) ([]result, error) {
+	var resp struct {
+		Value []struct {
+			Name          string         `json:"name"`
+			HostPageURL   string         `json:"hostPageUrl"`
+			Description   string         `json:"description"`
+			DatePublished string         `json:"datePublished"`
+			Duration      string         `json:"duration"`
+			ThumbnailURL  string         `json:"thumbnailUrl"`
+			Publisher     []bingProvider `json:"publisher"`
+		} `json:"value"`
+	}
+	err := json.NewDecoder(body).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range resp.Value {
+		res := result{
+			Title:     r.Name,
+			URL:       r.HostPageURL,
+			Blurb:     r.Description,
+			Date:      r.DatePublished,
+			Duration:  clockDuration(r.Duration),
+			Thumbnail: r.ThumbnailURL,
+		}
+		if len(r.Publisher) > 0 {
+			res.Source = r.Publisher[0].Name
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -0,0 +1,324 @@
+package search_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+type verticalResult struct {
+	Engine    string `json:"engine"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Blurb     string `json:"blurb"`
+	Date      string `json:"date"`
+	Source    string `json:"source"`
+	Image     string `json:"image"`
+	Thumbnail string `json:"thumbnail"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Duration  string `json:"duration"`
+}
+
+func TestVerticals(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		vertical string
+		engine   string
+		fixture  string
+		api      bool
+		n        int
+		want     verticalResult
+	}{
+		{
+			vertical: "news",
+			engine:   "bing",
+			fixture:  "bing-news.html",
+			n:        2,
+			want: verticalResult{
+				Title:  "Go 1.20 is released!",
+				URL:    "https://go.dev/blog/go1.20",
+				Blurb:  "Today the Go team is thrilled to release Go 1.20, which you can get by visiting the download page.",
+				Date:   "2 days ago",
+				Source: "The Go Blog",
+			},
+		},
+		{
+			vertical: "news",
+			engine:   "brave",
+			fixture:  "brave-news.html",
+			n:        2,
+			want: verticalResult{
+				Title:  "Go 1.20 is released!",
+				URL:    "https://go.dev/blog/go1.20",
+				Blurb:  "Today the Go team is thrilled to release Go 1.20, which you can get by visiting the download page.",
+				Date:   "2 days ago",
+				Source: "go.dev",
+			},
+		},
+		{
+			vertical: "news",
+			engine:   "mojeek",
+			fixture:  "mojeek-news.html",
+			n:        2,
+			want: verticalResult{
+				Title:  "Go 1.20 is released!",
+				URL:    "https://go.dev/blog/go1.20",
+				Blurb:  "Today the Go team is thrilled to release Go 1.20, which you can get by visiting the download page.",
+				Date:   "1 Feb 2023",
+				Source: "go.dev",
+			},
+		},
+		{
+			vertical: "news",
+			engine:   "yahoo",
+			fixture:  "yahoo-news.html",
+			n:        2,
+			want: verticalResult{
+				Title:  "Go 1.20 is released!",
+				URL:    "https://go.dev/blog/go1.20",
+				Blurb:  "Today the Go team is thrilled to release Go 1.20, which you can get by visiting the download page.",
+				Date:   "2 days ago",
+				Source: "The Go Blog",
+			},
+		},
+		{
+			vertical: "news",
+			engine:   "brave",
+			fixture:  "brave-api-news.json",
+			api:      true,
+			n:        1,
+			want: verticalResult{
+				Title:     "Go 1.20 is released!",
+				URL:       "https://go.dev/blog/go1.20",
+				Blurb:     "Today the Go team is thrilled to release Go 1.20, which you can get by visiting the download page.",
+				Date:      "2 days ago",
+				Source:    "go.dev",
+				Thumbnail: "https://imgs.search.brave.com/news1.jpg",
+			},
+		},
+		{
+			vertical: "news",
+			engine:   "bing",
+			fixture:  "bing-api-news.json",
+			api:      true,
+			n:        1,
+			want: verticalResult{
+				Title:     "Go 1.20 is released!",
+				URL:       "https://go.dev/blog/go1.20",
+				Blurb:     "Today the Go team is thrilled to release Go 1.20, which you can get by visiting the download page.",
+				Date:      "2023-02-01T18:00:00.0000000Z",
+				Source:    "The Go Blog",
+				Thumbnail: "https://www.bing.com/th?id=ON.1",
+			},
+		},
+		{
+			vertical: "images",
+			engine:   "bing",
+			fixture:  "bing-images.html",
+			n:        2,
+			want: verticalResult{
+				Title:     "The Go Gopher",
+				URL:       "https://go.dev/blog/gopher",
+				Blurb:     "The Go gopher, by Renee French",
+				Image:     "https://go.dev/blog/gopher/header.jpg",
+				Thumbnail: "https://tse1.mm.bing.net/th?id=OIP.1",
+				Width:     1920,
+				Height:    1080,
+			},
+		},
+		{
+			vertical: "images",
+			engine:   "searxng",
+			fixture:  "searxng-images.json",
+			n:        1,
+			want: verticalResult{
+				Title:     "The Go Gopher",
+				URL:       "https://go.dev/blog/gopher",
+				Blurb:     "The Go gopher, by Renee French",
+				Image:     "https://go.dev/blog/gopher/header.jpg",
+				Thumbnail: "https://go.dev/blog/gopher/header-small.jpg",
+				Width:     1920,
+				Height:    1080,
+			},
+		},
+		{
+			vertical: "images",
+			engine:   "brave",
+			fixture:  "brave-api-images.json",
+			api:      true,
+			n:        1,
+			want: verticalResult{
+				Title:     "The Go Gopher",
+				URL:       "https://go.dev/blog/gopher",
+				Source:    "go.dev",
+				Image:     "https://go.dev/blog/gopher/header.jpg",
+				Thumbnail: "https://imgs.search.brave.com/gopher-small.jpg",
+				Width:     1920,
+				Height:    1080,
+			},
+		},
+		{
+			vertical: "images",
+			engine:   "bing",
+			fixture:  "bing-api-images.json",
+			api:      true,
+			n:        1,
+			want: verticalResult{
+				Title:     "The Go Gopher",
+				URL:       "https://go.dev/blog/gopher",
+				Image:     "https://go.dev/blog/gopher/header.jpg",
+				Thumbnail: "https://tse1.mm.bing.net/th?id=OIP.1",
+				Width:     1920,
+				Height:    1080,
+			},
+		},
+		{
+			vertical: "videos",
+			engine:   "bing",
+			fixture:  "bing-videos.html",
+			n:        2,
+			want: verticalResult{
+				Title:     "Go in 100 Seconds",
+				URL:       "https://www.youtube.com/watch?v=YS4e4q9oBaU",
+				Source:    "Fireship",
+				Thumbnail: "https://tse1.mm.bing.net/th?id=OVP.1",
+				Duration:  "2:21",
+			},
+		},
+		{
+			vertical: "videos",
+			engine:   "brave",
+			fixture:  "brave-api-videos.json",
+			api:      true,
+			n:        1,
+			want: verticalResult{
+				Title:     "Go in 100 Seconds",
+				URL:       "https://www.youtube.com/watch?v=YS4e4q9oBaU",
+				Blurb:     "Go is a statically typed language developed at Google.",
+				Date:      "November 2, 2021",
+				Source:    "Fireship",
+				Thumbnail: "https://imgs.search.brave.com/video1.jpg",
+				Duration:  "02:21",
+			},
+		},
+		{
+			vertical: "videos",
+			engine:   "bing",
+			fixture:  "bing-api-videos.json",
+			api:      true,
+			n:        1,
+			want: verticalResult{
+				Title:     "Go in 100 Seconds",
+				URL:       "https://www.youtube.com/watch?v=YS4e4q9oBaU",
+				Blurb:     "Go is a statically typed language developed at Google.",
+				Date:      "2021-11-02T16:30:00.0000000",
+				Source:    "YouTube",
+				Thumbnail: "https://tse1.mm.bing.net/th?id=OVP.1",
+				Duration:  "2:21",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.vertical+"/"+tt.fixture, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.ServeFile(w, r, "testdata/"+tt.fixture)
+			}))
+			defer ts.Close()
+			opts := []search.Option{search.WithBaseURL(tt.engine, ts.URL+"/search?q=")}
+			if tt.api {
+				opts = append(opts, search.WithAPIKey(tt.engine, "secret"))
+			}
+			sv, err := search.NewServer([]string{"-type", tt.vertical}, time.Second, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			sv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&engines="+tt.engine, nil))
+			var resp struct {
+				Results []verticalResult `json:"results"`
+			}
+			err = json.NewDecoder(rec.Body).Decode(&resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != tt.n {
+				t.Fatalf("want %d results, got %+v", tt.n, resp.Results)
+			}
+			tt.want.Engine = tt.engine
+			if resp.Results[0] != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, resp.Results[0])
+			}
+		})
+	}
+}
+
+func TestVerticalSkipsEngines(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	s, err := search.NewSearcher(
+		search.WithOutput(&buf),
+		search.FromArgs([]string{"-s", "golang", "-n", "-type", "news"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreateQueries()
+	var urls []string
+	for u := range s.FormatURL() {
+		urls = append(urls, u)
+	}
+	// privacy mode: duck, qwant, and startpage have no news search
+	want := []string{"https://search.brave.com/news?q=golang", "https://www.mojeek.com/search?fmt=news&q=golang"}
+	if strings.Join(urls, " ") != strings.Join(want, " ") {
+		t.Errorf("want %v, got %v", want, urls)
+	}
+	if got := strings.Join(s.Skipped(), ","); got != "duck,qwant,startpage" {
+		t.Errorf("want duck,qwant,startpage skipped, got %s", got)
+	}
+}
+
+func TestVerticalText(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		vertical string
+		r        search.Result
+		want     string
+	}{
+		{
+			vertical: "images",
+			r:        search.Result{Engine: "brave", Title: "Gopher", URL: "https://example.com/gopher", Source: "example.com", Image: "https://example.com/gopher.png", Width: 100, Height: 50},
+			want:     "Gopher [brave]\nexample.com · 100x50\nhttps://example.com/gopher.png\n\n",
+		},
+		{
+			vertical: "videos",
+			r:        search.Result{Engine: "bing", Title: "Gophercon", URL: "https://example.com/talk", Duration: "12:34"},
+			want:     "Gophercon [bing]\n12:34\nhttps://example.com/talk\n\n",
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		s, err := search.NewSearcher(
+			search.WithOutput(&buf),
+			search.FromArgs([]string{"-s", "gopher", "-n", "-type", tt.vertical}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Write([]search.Result{tt.r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: want %q, got %q", tt.vertical, tt.want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+)
+
+// clock tells the time and waits, so watch can be tested without
+// waiting.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// watchState records when each url was first seen for each query.
+// It's saved after every check so a restarted watch doesn't report
+// the same urls again.
+type watchState struct {
+	path string
+	Seen map[string]map[string]time.Time `json:"seen"`
+}
+
+// loadWatchState reads the state at path. A missing file is an
+// empty state.
+func loadWatchState(path string) (*watchState, error) {
+	st := &watchState{path: path, Seen: make(map[string]map[string]time.Time)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read watch state: %w", err)
+	}
+	err = json.Unmarshal(b, st)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read watch state from %s: %w", path, err)
+	}
+	if st.Seen == nil {
+		st.Seen = make(map[string]map[string]time.Time)
+	}
+	return st, nil
+}
+
+// add marks the results as seen at now, returning those that
+// weren't seen before. A url found by several engines is returned
+// once.
+func (st *watchState) add(results []result, now time.Time) []result {
+	var fresh []result
+	for _, r := range results {
+		if r.URL == "" {
+			continue
+		}
+		seen, ok := st.Seen[r.Query]
+		if !ok {
+			seen = make(map[string]time.Time)
+			st.Seen[r.Query] = seen
+		}
+		if _, ok := seen[r.URL]; ok {
+			continue
+		}
+		seen[r.URL] = now
+		fresh = append(fresh, r)
+	}
+	return fresh
+}
+
+// save writes the state to a temporary file and renames it into
+// place, so an interrupted save leaves the old state intact.
+func (st *watchState) save() error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(st.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to save watch state: %w", err)
+	}
+	tmp := st.path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to save watch state: %w", err)
+	}
+	return os.Rename(tmp, st.path)
+}
+
+// watcher runs the same search every so often and reports urls it
+// hasn't seen before.
+type watcher struct {
+	s     *searcher
+	state *watchState
+	clock clock
+	every time.Duration
+	json  bool
+	out   io.Writer
+}
+
+// newWatcher returns a watcher for the search described by args,
+// which are passed to FromArgs, keeping its state at statePath.
+func newWatcher(args []string, every time.Duration, statePath string, out io.Writer, opts ...option) (*watcher, error) {
+	if every <= 0 {
+		return nil, errors.New("every must be greater than 0")
+	}
+	opts = append(append([]option{}, opts...), WithOutput(out), FromArgs(args))
+	s, err := NewSearcher(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.CreateQueries()
+	state, err := loadWatchState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{
+		s:     s,
+		state: state,
+		clock: realClock{},
+		every: every,
+		out:   out,
+	}, nil
+}
+
+// watchReport is a check's new urls, as written with -json.
+type watchReport struct {
+	Time time.Time `json:"time"`
+	New  []result  `json:"new"`
+}
+
+// check searches once, records what it found, and reports anything
+// new, passing it on to -webhook and -exec.
+func (w *watcher) check(ctx context.Context) error {
+	var results []result
+	for r := range w.s.results(ctx) {
+		results = append(results, r)
+	}
+	now := w.clock.Now()
+	fresh := w.state.add(results, now)
+	err := w.state.save()
+	if err != nil {
+		return err
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	if w.json {
+		err = json.NewEncoder(w.out).Encode(watchReport{Time: now, New: fresh})
+	} else {
+		fmt.Fprintf(w.out, "%s: %d new\n\n", now.Local().Format("2006-01-02 15:04"), len(fresh))
+		for _, r := range fresh {
+			w.s.print(r)
+		}
+	}
+	for _, err := range w.s.deliver(ctx, fresh) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return err
+}
+
+// run checks right away and then every w.every until ctx is done.
+// A failed check is reported and the watch goes on.
+func (w *watcher) run(ctx context.Context) error {
+	for {
+		err := w.check(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.clock.After(w.every):
+		}
+	}
+}
+
+// runWatch watches a search. -every sets how often to search,
+// -state where to keep the urls already seen, -json writes a json
+// line per check with new urls, and -once checks a single time, for
+// running from cron. Any other flags are passed to FromArgs.
+func runWatch(args []string, out io.Writer, opts ...option) error {
+	fset := flag.NewFlagSet("watch", flag.ContinueOnError)
+	every := fset.Duration("every", 6*time.Hour, "how often to search")
+	state := fset.String("state", "", "file to keep seen urls in")
+	asJSON := fset.Bool("json", false, "write json")
+	once := fset.Bool("once", false, "check once and exit")
+	own, rest := splitFlags(args, "every", "state")
+	bools, rest := splitBools(rest, "json", "once")
+	err := fset.Parse(append(own, bools...))
+	if err != nil {
+		return err
+	}
+	if *state == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return fmt.Errorf("unable to find watch state: %w", err)
+		}
+		*state = filepath.Join(dir, "search", "watch.json")
+	}
+	w, err := newWatcher(rest, *every, *state, out, opts...)
+	if err != nil {
+		return err
+	}
+	w.json = *asJSON
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *once {
+		return w.check(ctx)
+	}
+	return w.run(ctx)
+}
@@ -0,0 +1,128 @@
+package search_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davemolk/search"
+)
+
+// fakeClock starts at a fixed time and moves forward whenever the
+// watch waits, ending the watch with cancel after ticks waits.
+type fakeClock struct {
+	now    time.Time
+	ticks  int
+	cancel context.CancelFunc
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	if c.ticks == 0 {
+		c.cancel()
+		return ch
+	}
+	c.ticks--
+	c.now = c.now.Add(d)
+	ch <- c.now
+	return ch
+}
+
+var _ search.Clock = (*fakeClock)(nil)
+
+type watchReport struct {
+	Time time.Time `json:"time"`
+	New  []struct {
+		Query string `json:"query"`
+		URL   string `json:"url"`
+	} `json:"new"`
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	page, err := os.ReadFile("testdata/brave.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cobra is swapped for viper from the third search on
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := string(page)
+		if atomic.AddInt32(&requests, 1) > 2 {
+			body = strings.ReplaceAll(body, "spf13/cobra", "spf13/viper")
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	state := filepath.Join(t.TempDir(), "watch.json")
+	args := []string{"-s", "golang", "-n", "-engines", "brave"}
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	watch := func(ticks int) []watchReport {
+		t.Helper()
+		var out bytes.Buffer
+		w, err := search.NewWatcher(args, 6*time.Hour, state, &out, search.WithBaseURL("brave", ts.URL+"/brave?q="))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err = w.Run(ctx, &fakeClock{now: start, ticks: ticks, cancel: cancel}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reports []watchReport
+		dec := json.NewDecoder(&out)
+		for dec.More() {
+			var r watchReport
+			err := dec.Decode(&r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reports = append(reports, r)
+		}
+		return reports
+	}
+
+	reports := watch(2)
+	if len(reports) != 2 {
+		t.Fatalf("want reports from the first and third checks, got %+v", reports)
+	}
+	if len(reports[0].New) != 2 || !reports[0].Time.Equal(start) {
+		t.Errorf("first check: want both urls at start, got %+v", reports[0])
+	}
+	if reports[0].New[0].Query != "golang" {
+		t.Errorf("want query golang, got %q", reports[0].New[0].Query)
+	}
+	if len(reports[1].New) != 1 || reports[1].New[0].URL != "https://github.com/spf13/viper" {
+		t.Errorf("third check: want only viper, got %+v", reports[1])
+	}
+	if !reports[1].Time.Equal(start.Add(12 * time.Hour)) {
+		t.Errorf("third check: want time %v, got %v", start.Add(12*time.Hour), reports[1].Time)
+	}
+
+	// a restarted watch remembers what it has seen
+	if reports := watch(0); len(reports) != 0 {
+		t.Fatalf("want no reports after restart, got %+v", reports)
+	}
+}
+
+func TestWatchEvery(t *testing.T) {
+	t.Parallel()
+	_, err := search.NewWatcher([]string{"-s", "golang", "-n"}, 0, filepath.Join(t.TempDir(), "watch.json"), &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("want error for -every 0")
+	}
+}
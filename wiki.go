@@ -0,0 +1,62 @@
+package search
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// wikiQueries sets up the MediaWiki engines, in the -lang edition,
+// or english.
+func (s *searcher) wikiQueries() {
+	lang := s.locale.language()
+	if lang == "" {
+		lang = "en"
+	}
+	s.wikipedia = mediaWiki("wikipedia", lang+".wikipedia.org")
+	s.wiktionary = mediaWiki("wiktionary", lang+".wiktionary.org")
+}
+
+// mediaWiki searches the wiki at host with its api.
+func mediaWiki(name, host string) *query {
+	return &query{
+		base:      "https://" + host + "/w/api.php?action=query&list=search&format=json&srsearch=",
+		decode:    decodeMediaWiki(host),
+		name:      name,
+		pageParam: "sroffset",
+		pageSize:  10,
+		pageStart: 0,
+	}
+}
+
+// wikiTitle turns a page title into the path of its canonical url.
+var wikiTitle = strings.NewReplacer(" ", "_", "%", "%25", "?", "%3F", "#", "%23", "\"", "%22")
+
+// decodeMediaWiki reads search results from the wiki at host. The
+// snippets mark matches with <span class="searchmatch">, which is
+// stripped.
+func decodeMediaWiki(host string) func(io.Reader) ([]result, error) {
+	return func(body io.Reader) ([]result, error) {
+		var resp struct {
+			Query struct {
+				Search []struct {
+					Title   string `json:"title"`
+					Snippet string `json:"snippet"`
+				} `json:"search"`
+			} `json:"query"`
+		}
+		err := json.NewDecoder(body).Decode(&resp)
+		if err != nil {
+			return nil, err
+		}
+		var results []result
+		for _, r := range resp.Query.Search {
+			results = append(results, result{
+				Title: r.Title,
+				URL:   "https://" + host + "/wiki/" + wikiTitle.Replace(r.Title),
+				Blurb: stripTags(r.Snippet),
+			})
+		}
+		return results, nil
+	}
+}